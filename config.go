@@ -2,6 +2,7 @@ package config
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -64,17 +65,8 @@ func ParseEnv(cfg interface{}) error {
 				if env == "" {
 					continue
 				}
-				switch el.Field(i).Kind() {
-				case reflect.String:
-					el.Field(i).SetString(env)
-				case reflect.Int:
-					num, _ := strconv.Atoi(env)
-					el.Field(i).SetInt(int64(num))
-				case reflect.Bool:
-					b, _ := strconv.ParseBool(env)
-					el.Field(i).SetBool(b)
-				default:
-					return errors.New("not implemented go type")
+				if err := setFieldString(el.Field(i), env); err != nil {
+					return err
 				}
 			}
 		}
@@ -82,6 +74,23 @@ func ParseEnv(cfg interface{}) error {
 	return nil
 }
 
+// setFieldString converts value to the field type, it is used for all sources which provide values as strings
+func setFieldString(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		num, _ := strconv.Atoi(value)
+		field.SetInt(int64(num))
+	case reflect.Bool:
+		b, _ := strconv.ParseBool(value)
+		field.SetBool(b)
+	default:
+		return errors.New("not implemented go type")
+	}
+	return nil
+}
+
 // ParseBytes parse input slice of bytes to cfg interface{} based on fileType (YAML, JSON, XML)
 // cfg should be passed as pointer
 func ParseBytes(data []byte, fileType FileType, cfg interface{}) (err error) {
@@ -102,7 +111,13 @@ func ParseBytes(data []byte, fileType FileType, cfg interface{}) (err error) {
 // Underneath using ParseBytes function
 // cfg should be passed as pointer
 func ParseReader(reader io.Reader, fileType FileType, cfg interface{}) (err error) {
-	if data, readErr := io.ReadAll(bufio.NewReader(reader)); readErr == nil {
+	return ParseReaderCtx(context.Background(), reader, fileType, cfg)
+}
+
+// ParseReaderCtx parse input io.Reader like ParseReader, reading stops with ctx error as soon as ctx is done
+// Read call which is already blocked is not interrupted, close the reader to unblock it
+func ParseReaderCtx(ctx context.Context, reader io.Reader, fileType FileType, cfg interface{}) (err error) {
+	if data, readErr := io.ReadAll(bufio.NewReader(contextReader{ctx: ctx, reader: reader})); readErr == nil {
 		return ParseBytes(data, fileType, cfg)
 	} else {
 		return readErr
@@ -113,6 +128,14 @@ func ParseReader(reader io.Reader, fileType FileType, cfg interface{}) (err erro
 // Underneath using ParseReader function
 // cfg should be passed as pointer
 func ParseFile(filePath string, fileType FileType, cfg interface{}) (err error) {
+	return ParseFileCtx(context.Background(), filePath, fileType, cfg)
+}
+
+// ParseFileCtx parse file like ParseFile, reading stops with ctx error as soon as ctx is done
+func ParseFileCtx(ctx context.Context, filePath string, fileType FileType, cfg interface{}) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
 	f, fErr := os.Open(filePath)
 
 	if fErr != nil {
@@ -121,7 +144,20 @@ func ParseFile(filePath string, fileType FileType, cfg interface{}) (err error)
 	}
 	defer CloseResources(f)
 
-	return ParseReader(f, fileType, cfg)
+	return ParseReaderCtx(ctx, f, fileType, cfg)
+}
+
+// contextReader fails reads after ctx is done
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
 }
 
 func unmarshallJSON(data []byte, cfg interface{}) error {
@@ -1,13 +1,18 @@
 package config_test
 
 import (
+	"context"
 	"encoding/xml"
+	"errors"
 	"io"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vielendanke/go-config"
+	"github.com/vielendanke/go-config/configtest"
 )
 
 type TestConfig struct {
@@ -291,3 +296,58 @@ func TestParseFileXML_Success(t *testing.T) {
 	assert.Equal(t, 2, cfgForParse.Second)
 	assert.Equal(t, "first_inner", cfgForParse.InnerThird.FirstInner)
 }
+
+func TestNewConfigCtx_Cancelled(t *testing.T) {
+	// prepare
+	cfgForParse := &TestConfig{
+		InnerThird: &InnerTestConfig{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// make test
+	err := config.NewConfigCtx(ctx, cfgForParse, config.WithParsingFile("test.json", config.JSON))
+
+	// assertions
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, "", cfgForParse.First)
+}
+
+func TestParseReaderCtx_Cancelled(t *testing.T) {
+	// prepare
+	cfgForParse := &TestConfig{
+		InnerThird: &InnerTestConfig{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// make test
+	err := config.ParseReaderCtx(ctx, strings.NewReader(`{"first": "first"}`), config.JSON, cfgForParse)
+
+	// assertions
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, "", cfgForParse.First)
+}
+
+func TestNewConfigCtx_VaultDeadline(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteSecret("secret/app", map[string]interface{}{"first": "first"})
+	vault.SetLatency(5 * time.Second)
+	cli, cliErr := config.NewVaultClient(config.WithVaultAddress(vault.URL()), config.WithVaultToken(vault.RootToken()))
+	assert.Nil(t, cliErr)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cfgForParse := &TestConfig{
+		InnerThird: &InnerTestConfig{},
+	}
+	started := time.Now()
+
+	// make test
+	err := config.NewConfigCtx(ctx, cfgForParse, config.WithParsingVaultPaths(cli, "secret/app"))
+
+	// assertions
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(started), time.Second)
+	assert.Equal(t, "", cfgForParse.First)
+}
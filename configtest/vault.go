@@ -0,0 +1,325 @@
+/*
+Package configtest provides in-memory fakes of config sources for tests which should run offline
+
+Example:
+vault := configtest.NewVault(t)
+vault.WriteSecret("secret/app", map[string]interface{}{"password": "pass"})
+cli, err := config.NewVaultClient(config.WithVaultAddress(vault.URL()), config.WithVaultToken(vault.RootToken()))
+*/
+package configtest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	defaultRootToken = "root"
+	defaultKVMount   = "secret/"
+	vaultTokenHeader = "X-Vault-Token"
+)
+
+type vaultSettings struct {
+	rootToken string
+	mounts    map[string]int
+}
+
+type vaultOption func(s *vaultSettings)
+
+// WithRootToken sets token which never expires, default is "root"
+func WithRootToken(token string) vaultOption {
+	return func(s *vaultSettings) {
+		s.rootToken = token
+	}
+}
+
+// WithKVMount mounts KV engine of version 1 or 2 at path, KV v2 is mounted at "secret/" by default like in Vault dev server
+func WithKVMount(path string, version int) vaultOption {
+	return func(s *vaultSettings) {
+		s.mounts[strings.Trim(path, "/")+"/"] = version
+	}
+}
+
+/*
+Vault is in-memory fake Vault server built on httptest.Server
+It serves KV v1 and v2 secrets, token lookup and renewal, AppRole and Kubernetes login, leases and sys/health,
+faults are injected with SetLatency, FailNext and Seal
+Every request except login and sys/health should be sent with a valid token, otherwise 403 is returned
+
+Example:
+vault := configtest.NewVault(t, configtest.WithKVMount("kv", 1))
+vault.WriteSecret("kv/app", map[string]interface{}{"host": "db"})
+vault.FailNext(2, http.StatusBadGateway)
+*/
+type Vault struct {
+	server    *httptest.Server
+	rootToken string
+
+	mu       sync.Mutex
+	mounts   map[string]int
+	kv       map[string]*kvSecret
+	tokens   map[string]*vaultToken
+	appRoles map[string]*appRole
+	k8sRoles map[string]*kubernetesRole
+	dynamic  map[string]*dynamicSecret
+	leases   map[string]*vaultLease
+	serial   int
+	requests int
+
+	latency    time.Duration
+	failStatus int
+	failCount  int
+	sealed     bool
+}
+
+// NewVault starts fake Vault, it is closed when test finishes
+func NewVault(t testing.TB, opts ...vaultOption) *Vault {
+	s := &vaultSettings{rootToken: defaultRootToken, mounts: map[string]int{defaultKVMount: 2}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	v := &Vault{
+		rootToken: s.rootToken,
+		mounts:    s.mounts,
+		kv:        map[string]*kvSecret{},
+		tokens:    map[string]*vaultToken{s.rootToken: {id: s.rootToken, policies: []string{"root"}}},
+		appRoles:  map[string]*appRole{},
+		k8sRoles:  map[string]*kubernetesRole{},
+		dynamic:   map[string]*dynamicSecret{},
+		leases:    map[string]*vaultLease{},
+	}
+	v.server = httptest.NewServer(http.HandlerFunc(v.serveHTTP))
+	t.Cleanup(v.Close)
+	return v
+}
+
+// URL returns address of fake Vault, e.g. for VAULT_ADDR
+func (v *Vault) URL() string {
+	return v.server.URL
+}
+
+// RootToken returns token which never expires
+func (v *Vault) RootToken() string {
+	return v.rootToken
+}
+
+// Close stops fake Vault, it is called automatically when test finishes
+func (v *Vault) Close() {
+	v.server.Close()
+}
+
+// Requests returns number of requests received by fake Vault
+func (v *Vault) Requests() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.requests
+}
+
+// SetLatency delays every response by d
+func (v *Vault) SetLatency(d time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.latency = d
+}
+
+// FailNext responds to the next n requests with status, e.g. http.StatusInternalServerError
+func (v *Vault) FailNext(n, status int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.failCount, v.failStatus = n, status
+}
+
+// Seal makes fake Vault respond with 503 to every request until Unseal is called
+func (v *Vault) Seal() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.sealed = true
+}
+
+// Unseal reverts Seal
+func (v *Vault) Unseal() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.sealed = false
+}
+
+// vaultRequest is parsed request passed to handlers
+type vaultRequest struct {
+	method string
+	path   string
+	token  string
+	query  map[string][]string
+	body   map[string]interface{}
+	// contentType is checked for PATCH requests like Vault does
+	contentType string
+}
+
+// vaultResponse is written as JSON, nil body means 204 No Content
+type vaultResponse struct {
+	status int
+	body   map[string]interface{}
+}
+
+func (v *Vault) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	v.requests++
+	latency := v.latency
+	v.mu.Unlock()
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	req, err := parseVaultRequest(r)
+	if err != nil {
+		writeResponse(w, errorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+	v.mu.Lock()
+	resp := v.handle(req)
+	v.mu.Unlock()
+	writeResponse(w, resp)
+}
+
+func parseVaultRequest(r *http.Request) (*vaultRequest, error) {
+	req := &vaultRequest{
+		method: r.Method,
+		path:   strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/"),
+		token:  r.Header.Get(vaultTokenHeader),
+		query:  r.URL.Query(),
+
+		contentType: r.Header.Get("Content-Type"),
+	}
+	if r.Method == "LIST" || r.URL.Query().Get("list") == "true" {
+		req.method = "LIST"
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &req.body); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// handle routes request, it is called under lock
+func (v *Vault) handle(req *vaultRequest) vaultResponse {
+	if v.failCount > 0 {
+		v.failCount--
+		return errorResponse(v.failStatus, "injected failure")
+	}
+	if req.path == "sys/health" {
+		return v.health(req)
+	}
+	if v.sealed {
+		return errorResponse(http.StatusServiceUnavailable, "Vault is sealed")
+	}
+	if strings.HasPrefix(req.path, "auth/") && strings.HasSuffix(req.path, "/login") {
+		return v.login(req)
+	}
+	if !v.authorized(req.token) {
+		return errorResponse(http.StatusForbidden, "permission denied")
+	}
+
+	switch {
+	case strings.HasPrefix(req.path, "auth/token/"):
+		return v.handleToken(req)
+	case strings.HasPrefix(req.path, "sys/leases/"):
+		return v.handleLease(req)
+	case strings.HasPrefix(req.path, "sys/internal/ui/mounts/"):
+		return v.mountInfo(strings.TrimPrefix(req.path, "sys/internal/ui/mounts/"))
+	}
+	if d, ok := v.dynamic[req.path]; ok && req.method == http.MethodGet {
+		return v.issueLease(d)
+	}
+	if mount, version, ok := v.findMount(req.path); ok {
+		if version == 2 {
+			return v.handleKV2(req, mount)
+		}
+		return v.handleKV1(req)
+	}
+	return notFoundResponse()
+}
+
+// health responds like sys/health, status of sealed Vault could be overridden with sealedcode parameter
+func (v *Vault) health(req *vaultRequest) vaultResponse {
+	status := http.StatusOK
+	if v.sealed {
+		status = http.StatusServiceUnavailable
+		if code, err := strconv.Atoi(firstQuery(req.query, "sealedcode")); err == nil {
+			status = code
+		}
+	}
+	return vaultResponse{status: status, body: map[string]interface{}{
+		"initialized": true,
+		"sealed":      v.sealed,
+		"standby":     false,
+	}}
+}
+
+// findMount returns the longest KV mount serving path
+func (v *Vault) findMount(path string) (string, int, bool) {
+	var found string
+	for mount := range v.mounts {
+		if strings.HasPrefix(path+"/", mount) && len(mount) > len(found) {
+			found = mount
+		}
+	}
+	if found == "" {
+		return "", 0, false
+	}
+	return found, v.mounts[found], true
+}
+
+func (v *Vault) mountInfo(path string) vaultResponse {
+	mount, version, ok := v.findMount(path)
+	if !ok {
+		return errorResponse(http.StatusBadRequest, "preflight capability check returned 403, please ensure client's policies grant access to path")
+	}
+	return dataResponse(map[string]interface{}{
+		"path":    mount,
+		"type":    "kv",
+		"options": map[string]interface{}{"version": strconv.Itoa(version)},
+	})
+}
+
+func (v *Vault) nextID(prefix string) string {
+	v.serial++
+	return prefix + strconv.Itoa(v.serial)
+}
+
+func writeResponse(w http.ResponseWriter, resp vaultResponse) {
+	if resp.body == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.status)
+	_ = json.NewEncoder(w).Encode(resp.body)
+}
+
+func dataResponse(data map[string]interface{}) vaultResponse {
+	return vaultResponse{status: http.StatusOK, body: map[string]interface{}{"data": data}}
+}
+
+// notFoundResponse is returned by Vault when there is nothing under path
+func notFoundResponse() vaultResponse {
+	return vaultResponse{status: http.StatusNotFound, body: map[string]interface{}{"errors": []string{}}}
+}
+
+func errorResponse(status int, message string) vaultResponse {
+	return vaultResponse{status: status, body: map[string]interface{}{"errors": []string{message}}}
+}
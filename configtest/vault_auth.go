@@ -0,0 +1,260 @@
+package configtest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type vaultToken struct {
+	id        string
+	policies  []string
+	ttl       time.Duration
+	renewable bool
+	expires   time.Time
+}
+
+type appRole struct {
+	secretID string
+	ttl      time.Duration
+}
+
+type kubernetesRole struct {
+	jwt string
+	ttl time.Duration
+}
+
+// dynamicSecret is returned with a new lease on each read, like database credentials
+type dynamicSecret struct {
+	path      string
+	data      map[string]interface{}
+	ttl       time.Duration
+	renewable bool
+}
+
+type vaultLease struct {
+	id        string
+	ttl       time.Duration
+	renewable bool
+	expires   time.Time
+}
+
+// CreateToken creates token which expires after ttl, 0 means it never expires
+func (v *Vault) CreateToken(ttl time.Duration, renewable bool) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.createToken(ttl, renewable).id
+}
+
+// RevokeToken revokes token, requests with it are rejected with 403
+func (v *Vault) RevokeToken(token string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.tokens, token)
+}
+
+// AddAppRole allows AppRole login with roleID and secretID, issued tokens are renewable and expire after ttl
+func (v *Vault) AddAppRole(roleID, secretID string, ttl time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.appRoles[roleID] = &appRole{secretID: secretID, ttl: ttl}
+}
+
+// AddKubernetesRole allows Kubernetes login to role with service account jwt, issued tokens are renewable and expire after ttl
+func (v *Vault) AddKubernetesRole(role, jwt string, ttl time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.k8sRoles[role] = &kubernetesRole{jwt: jwt, ttl: ttl}
+}
+
+/*
+WriteLeasedSecret stores dynamic secret under path, e.g. "database/creds/app"
+Each read returns data with a new lease which expires after ttl, leases are renewed with sys/leases/renew
+*/
+func (v *Vault) WriteLeasedSecret(path string, data map[string]interface{}, ttl time.Duration, renewable bool) {
+	path = strings.Trim(path, "/")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.dynamic[path] = &dynamicSecret{path: path, data: copyData(data), ttl: ttl, renewable: renewable}
+}
+
+// Leases returns sorted ids of leases which are not expired or revoked
+func (v *Vault) Leases() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var ids []string
+	for id, lease := range v.leases {
+		if time.Now().Before(lease.expires) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RevokeLease revokes lease, its renewal fails afterwards
+func (v *Vault) RevokeLease(id string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.leases, id)
+}
+
+func (v *Vault) createToken(ttl time.Duration, renewable bool) *vaultToken {
+	token := &vaultToken{id: v.nextID("s.token"), policies: []string{"default"}, ttl: ttl, renewable: renewable}
+	if ttl > 0 {
+		token.expires = time.Now().Add(ttl)
+	}
+	v.tokens[token.id] = token
+	return token
+}
+
+func (v *Vault) authorized(id string) bool {
+	token, ok := v.tokens[id]
+	return ok && (token.expires.IsZero() || time.Now().Before(token.expires))
+}
+
+func (v *Vault) login(req *vaultRequest) vaultResponse {
+	if roleID, ok := req.body["role_id"].(string); ok {
+		secretID, _ := req.body["secret_id"].(string)
+		role, exists := v.appRoles[roleID]
+		if !exists || role.secretID != secretID {
+			return errorResponse(http.StatusBadRequest, "invalid role or secret ID")
+		}
+		return authResponse(v.createToken(role.ttl, true), role.ttl)
+	}
+	if jwt, ok := req.body["jwt"].(string); ok {
+		name, _ := req.body["role"].(string)
+		role, exists := v.k8sRoles[name]
+		if !exists || role.jwt != jwt {
+			return errorResponse(http.StatusForbidden, "permission denied")
+		}
+		return authResponse(v.createToken(role.ttl, true), role.ttl)
+	}
+	return errorResponse(http.StatusBadRequest, "unsupported login method")
+}
+
+func (v *Vault) handleToken(req *vaultRequest) vaultResponse {
+	switch strings.TrimPrefix(req.path, "auth/token/") {
+	case "lookup-self":
+		token := v.tokens[req.token]
+		ttl := 0
+		expireTime := interface{}(nil)
+		if !token.expires.IsZero() {
+			ttl = int(time.Until(token.expires).Seconds())
+			expireTime = formatTime(token.expires)
+		}
+		return dataResponse(map[string]interface{}{
+			"id":          token.id,
+			"policies":    token.policies,
+			"ttl":         ttl,
+			"renewable":   token.renewable,
+			"expire_time": expireTime,
+		})
+	case "renew-self":
+		token := v.tokens[req.token]
+		if !token.renewable {
+			return errorResponse(http.StatusBadRequest, "lease is not renewable")
+		}
+		ttl := token.ttl
+		if increment := durationValue(req.body["increment"]); increment > 0 {
+			ttl = increment
+		}
+		if ttl > 0 {
+			token.expires = time.Now().Add(ttl)
+		}
+		return authResponse(token, ttl)
+	case "create":
+		renewable := true
+		if r, ok := req.body["renewable"].(bool); ok {
+			renewable = r
+		}
+		ttl := durationValue(req.body["ttl"])
+		return authResponse(v.createToken(ttl, renewable), ttl)
+	case "revoke-self":
+		delete(v.tokens, req.token)
+		return vaultResponse{}
+	}
+	return notFoundResponse()
+}
+
+func (v *Vault) issueLease(d *dynamicSecret) vaultResponse {
+	lease := &vaultLease{id: v.nextID(d.path + "/"), ttl: d.ttl, renewable: d.renewable, expires: time.Now().Add(d.ttl)}
+	v.leases[lease.id] = lease
+	return vaultResponse{status: http.StatusOK, body: map[string]interface{}{
+		"lease_id":       lease.id,
+		"lease_duration": int(d.ttl.Seconds()),
+		"renewable":      d.renewable,
+		"data":           copyData(d.data),
+	}}
+}
+
+func (v *Vault) handleLease(req *vaultRequest) vaultResponse {
+	id, _ := req.body["lease_id"].(string)
+	lease, ok := v.leases[id]
+	if ok && !time.Now().Before(lease.expires) {
+		delete(v.leases, id)
+		ok = false
+	}
+	switch strings.TrimPrefix(req.path, "sys/leases/") {
+	case "renew":
+		if !ok {
+			return errorResponse(http.StatusBadRequest, "lease not found")
+		}
+		if !lease.renewable {
+			return errorResponse(http.StatusBadRequest, "lease is not renewable")
+		}
+		ttl := lease.ttl
+		if increment := durationValue(req.body["increment"]); increment > 0 {
+			ttl = increment
+		}
+		lease.expires = time.Now().Add(ttl)
+		return vaultResponse{status: http.StatusOK, body: map[string]interface{}{
+			"lease_id":       lease.id,
+			"lease_duration": int(ttl.Seconds()),
+			"renewable":      true,
+		}}
+	case "lookup":
+		if !ok {
+			return errorResponse(http.StatusBadRequest, "invalid lease")
+		}
+		return dataResponse(map[string]interface{}{
+			"id":          lease.id,
+			"ttl":         int(time.Until(lease.expires).Seconds()),
+			"renewable":   lease.renewable,
+			"expire_time": formatTime(lease.expires),
+		})
+	case "revoke":
+		delete(v.leases, id)
+		return vaultResponse{}
+	}
+	return notFoundResponse()
+}
+
+// authResponse returns token with ttl it was issued or renewed for
+func authResponse(token *vaultToken, ttl time.Duration) vaultResponse {
+	return vaultResponse{status: http.StatusOK, body: map[string]interface{}{
+		"auth": map[string]interface{}{
+			"client_token":   token.id,
+			"policies":       token.policies,
+			"lease_duration": int(ttl.Seconds()),
+			"renewable":      token.renewable,
+		},
+	}}
+}
+
+// durationValue parses duration sent as seconds or as string, e.g. 3600, "3600" or "1h"
+func durationValue(value interface{}) time.Duration {
+	switch v := value.(type) {
+	case float64:
+		return time.Duration(v) * time.Second
+	case string:
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		d, _ := time.ParseDuration(v)
+		return d
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package configtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVault_AppRoleLogin(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.AddAppRole("role", "secret", time.Hour)
+	cli := newTestClient(t, v, "")
+
+	// make test
+	login, loginErr := cli.Logical().Write("auth/approle/login", map[string]interface{}{"role_id": "role", "secret_id": "secret"})
+	_, invalidErr := cli.Logical().Write("auth/approle/login", map[string]interface{}{"role_id": "role", "secret_id": "other"})
+
+	// assertions
+	assert.Nil(t, loginErr)
+	assert.NotNil(t, invalidErr)
+	assert.NotEmpty(t, login.Auth.ClientToken)
+	assert.Equal(t, 3600, login.Auth.LeaseDuration)
+}
+
+func TestVault_KubernetesLogin_TokenRenewal(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.AddKubernetesRole("app", "jwt", time.Minute)
+	cli := newTestClient(t, v, "")
+	login, loginErr := cli.Logical().Write("auth/kubernetes/login", map[string]interface{}{"role": "app", "jwt": "jwt"})
+	assert.Nil(t, loginErr)
+	cli.SetToken(login.Auth.ClientToken)
+
+	// make test
+	lookup, lookupErr := cli.Auth().Token().LookupSelf()
+	renewed, renewErr := cli.Auth().Token().RenewSelf(3600)
+	v.RevokeToken(login.Auth.ClientToken)
+	_, revokedErr := cli.Auth().Token().LookupSelf()
+
+	// assertions
+	assert.Nil(t, lookupErr)
+	assert.Nil(t, renewErr)
+	assert.NotNil(t, revokedErr)
+	renewable, _ := lookup.TokenIsRenewable()
+	assert.True(t, renewable)
+	assert.Equal(t, 3600, renewed.Auth.LeaseDuration)
+}
+
+func TestVault_TokenExpires(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	token := v.CreateToken(50*time.Millisecond, false)
+	cli := newTestClient(t, v, token)
+
+	// make test
+	_, validErr := cli.Auth().Token().LookupSelf()
+	time.Sleep(100 * time.Millisecond)
+	_, expiredErr := cli.Auth().Token().LookupSelf()
+
+	// assertions
+	assert.Nil(t, validErr)
+	assert.NotNil(t, expiredErr)
+}
+
+func TestVault_Leases(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.WriteLeasedSecret("database/creds/app", map[string]interface{}{"username": "user"}, time.Minute, true)
+	cli := newTestClient(t, v, v.RootToken())
+
+	// make test
+	first, firstErr := cli.Logical().Read("database/creds/app")
+	second, secondErr := cli.Logical().Read("database/creds/app")
+	renewed, renewErr := cli.Sys().Renew(first.LeaseID, 600)
+	revokeErr := cli.Sys().Revoke(second.LeaseID)
+	_, revokedErr := cli.Sys().Renew(second.LeaseID, 600)
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secondErr)
+	assert.Nil(t, renewErr)
+	assert.Nil(t, revokeErr)
+	assert.NotNil(t, revokedErr)
+	assert.NotEqual(t, first.LeaseID, second.LeaseID)
+	assert.Equal(t, "user", first.Data["username"])
+	assert.Equal(t, 600, renewed.LeaseDuration)
+	assert.Equal(t, []string{first.LeaseID}, v.Leases())
+}
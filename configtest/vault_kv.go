@@ -0,0 +1,291 @@
+package configtest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mergePatchContentType is required by KV v2 for patch requests
+const mergePatchContentType = "application/merge-patch+json"
+
+// kvSecret stores versions of KV secret, KV v1 secret always has a single version
+type kvSecret struct {
+	versions []*kvVersion
+	created  time.Time
+	updated  time.Time
+}
+
+type kvVersion struct {
+	data      map[string]interface{}
+	created   time.Time
+	deleted   time.Time
+	destroyed bool
+}
+
+func (s *kvSecret) latest() *kvVersion {
+	return s.versions[len(s.versions)-1]
+}
+
+// version returns version n, 0 means the latest one
+func (s *kvSecret) version(n int) (*kvVersion, int, bool) {
+	if n == 0 {
+		n = len(s.versions)
+	}
+	if n < 1 || n > len(s.versions) {
+		return nil, n, false
+	}
+	return s.versions[n-1], n, true
+}
+
+/*
+WriteSecret stores data under logical path, e.g. "secret/app", for KV v2 a new version is created
+It panics if there is no KV mount serving path, see WithKVMount
+*/
+func (v *Vault) WriteSecret(path string, data map[string]interface{}) {
+	path = strings.Trim(path, "/")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_, version, ok := v.findMount(path)
+	if !ok {
+		panic(fmt.Sprintf("configtest: no KV mount for path %s", path))
+	}
+	v.putKV(path, copyData(data), version == 2)
+}
+
+// Secret returns data of the latest version of secret stored under logical path, nil if it does not exist or was deleted
+func (v *Vault) Secret(path string) map[string]interface{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.kv[strings.Trim(path, "/")]
+	if !ok || !s.latest().deleted.IsZero() || s.latest().destroyed {
+		return nil
+	}
+	return copyData(s.latest().data)
+}
+
+// DeleteSecret deletes secret stored under logical path, for KV v2 only the latest version is deleted like "vault kv delete" does
+func (v *Vault) DeleteSecret(path string) {
+	path = strings.Trim(path, "/")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.deleteKV(path)
+}
+
+func (v *Vault) putKV(path string, data map[string]interface{}, versioned bool) *kvVersion {
+	now := time.Now().UTC()
+	version := &kvVersion{data: data, created: now}
+	s, ok := v.kv[path]
+	switch {
+	case !ok:
+		s = &kvSecret{created: now}
+		v.kv[path] = s
+		s.versions = []*kvVersion{version}
+	case versioned:
+		s.versions = append(s.versions, version)
+	default:
+		s.versions = []*kvVersion{version}
+	}
+	s.updated = now
+	return version
+}
+
+func (v *Vault) deleteKV(path string) {
+	s, ok := v.kv[path]
+	if !ok {
+		return
+	}
+	if _, version, _ := v.findMount(path); version == 2 {
+		s.latest().deleted = time.Now().UTC()
+		return
+	}
+	delete(v.kv, path)
+}
+
+func (v *Vault) handleKV1(req *vaultRequest) vaultResponse {
+	switch req.method {
+	case http.MethodGet:
+		s, ok := v.kv[req.path]
+		if !ok {
+			return notFoundResponse()
+		}
+		return dataResponse(copyData(s.latest().data))
+	case "LIST":
+		return v.listKV(req.path)
+	case http.MethodPut, http.MethodPost:
+		v.putKV(req.path, copyData(req.body), false)
+		return vaultResponse{}
+	case http.MethodDelete:
+		delete(v.kv, req.path)
+		return vaultResponse{}
+	}
+	return errorResponse(http.StatusMethodNotAllowed, "unsupported operation")
+}
+
+func (v *Vault) handleKV2(req *vaultRequest, mount string) vaultResponse {
+	rest := strings.TrimPrefix(req.path, mount)
+	switch {
+	case strings.HasPrefix(rest, "data/"):
+		return v.handleKV2Data(req, mount+strings.TrimPrefix(rest, "data/"))
+	case strings.HasPrefix(rest, "metadata/") || rest == "metadata":
+		path := strings.Trim(mount+strings.TrimPrefix(strings.TrimPrefix(rest, "metadata"), "/"), "/")
+		return v.handleKV2Metadata(req, path)
+	}
+	return notFoundResponse()
+}
+
+func (v *Vault) handleKV2Data(req *vaultRequest, path string) vaultResponse {
+	s, exists := v.kv[path]
+	switch req.method {
+	case http.MethodGet:
+		if !exists {
+			return notFoundResponse()
+		}
+		n, _ := strconv.Atoi(firstQuery(req.query, "version"))
+		version, n, ok := s.version(n)
+		if !ok {
+			return notFoundResponse()
+		}
+		metadata := versionMetadata(version, n)
+		if !version.deleted.IsZero() || version.destroyed {
+			return vaultResponse{status: http.StatusNotFound, body: map[string]interface{}{
+				"data": map[string]interface{}{"data": nil, "metadata": metadata},
+			}}
+		}
+		return dataResponse(map[string]interface{}{"data": copyData(version.data), "metadata": metadata})
+	case http.MethodPut, http.MethodPost, http.MethodPatch:
+		if req.method == http.MethodPatch && req.contentType != mergePatchContentType {
+			return errorResponse(http.StatusUnsupportedMediaType, "unsupported content type")
+		}
+		if options, ok := req.body["options"].(map[string]interface{}); ok && options["cas"] != nil {
+			current := 0
+			if exists {
+				current = len(s.versions)
+			}
+			if cas, _ := options["cas"].(float64); int(cas) != current {
+				return errorResponse(http.StatusBadRequest, "check-and-set parameter did not match the current version")
+			}
+		}
+		data, _ := req.body["data"].(map[string]interface{})
+		if req.method == http.MethodPatch {
+			if !exists || !s.latest().deleted.IsZero() || s.latest().destroyed {
+				return notFoundResponse()
+			}
+			data = mergePatch(copyData(s.latest().data), data)
+		}
+		version := v.putKV(path, copyData(data), true)
+		return dataResponse(versionMetadata(version, len(v.kv[path].versions)))
+	case http.MethodDelete:
+		v.deleteKV(path)
+		return vaultResponse{}
+	}
+	return errorResponse(http.StatusMethodNotAllowed, "unsupported operation")
+}
+
+func (v *Vault) handleKV2Metadata(req *vaultRequest, path string) vaultResponse {
+	switch req.method {
+	case "LIST":
+		return v.listKV(path)
+	case http.MethodGet:
+		s, ok := v.kv[path]
+		if !ok {
+			return notFoundResponse()
+		}
+		versions := make(map[string]interface{}, len(s.versions))
+		for i, version := range s.versions {
+			versions[strconv.Itoa(i+1)] = map[string]interface{}{
+				"created_time":  formatTime(version.created),
+				"deletion_time": formatTime(version.deleted),
+				"destroyed":     version.destroyed,
+			}
+		}
+		return dataResponse(map[string]interface{}{
+			"current_version": len(s.versions),
+			"oldest_version":  1,
+			"max_versions":    0,
+			"created_time":    formatTime(s.created),
+			"updated_time":    formatTime(s.updated),
+			"custom_metadata": nil,
+			"versions":        versions,
+		})
+	case http.MethodDelete:
+		delete(v.kv, path)
+		return vaultResponse{}
+	}
+	return errorResponse(http.StatusMethodNotAllowed, "unsupported operation")
+}
+
+// listKV returns direct children of logical prefix, folders end with "/"
+func (v *Vault) listKV(prefix string) vaultResponse {
+	prefix = strings.Trim(prefix, "/") + "/"
+	seen := map[string]bool{}
+	var keys []string
+	for path := range v.kv {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		key := strings.TrimPrefix(path, prefix)
+		if i := strings.Index(key, "/"); i >= 0 {
+			key = key[:i+1]
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return notFoundResponse()
+	}
+	sort.Strings(keys)
+	return dataResponse(map[string]interface{}{"keys": keys})
+}
+
+func versionMetadata(version *kvVersion, n int) map[string]interface{} {
+	return map[string]interface{}{
+		"version":         n,
+		"created_time":    formatTime(version.created),
+		"deletion_time":   formatTime(version.deleted),
+		"destroyed":       version.destroyed,
+		"custom_metadata": nil,
+	}
+}
+
+// mergePatch applies JSON merge patch, keys with nil value are removed
+func mergePatch(data, patch map[string]interface{}) map[string]interface{} {
+	for k, value := range patch {
+		if value == nil {
+			delete(data, k)
+			continue
+		}
+		data[k] = value
+	}
+	return data
+}
+
+func copyData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(data))
+	for k, value := range data {
+		copied[k] = value
+	}
+	return copied
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func firstQuery(query map[string][]string, key string) string {
+	if values := query[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package configtest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVault_KV2(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.WriteSecret("secret/app/db", map[string]interface{}{"user": "admin"})
+	cli := newTestClient(t, v, v.RootToken())
+
+	// make test
+	written, writeErr := cli.Logical().Write("secret/data/app/db", map[string]interface{}{
+		"data": map[string]interface{}{"user": "root"},
+	})
+	latest, latestErr := cli.Logical().Read("secret/data/app/db")
+	first, firstErr := cli.Logical().ReadWithData("secret/data/app/db", map[string][]string{"version": {"1"}})
+	list, listErr := cli.Logical().List("secret/metadata/app")
+	mount, mountErr := cli.Logical().Read("sys/internal/ui/mounts/secret/app/db")
+
+	// assertions
+	assert.Nil(t, writeErr)
+	assert.Nil(t, latestErr)
+	assert.Nil(t, firstErr)
+	assert.Nil(t, listErr)
+	assert.Nil(t, mountErr)
+	assert.Equal(t, "2", written.Data["version"].(interface{ String() string }).String())
+	assert.Equal(t, map[string]interface{}{"user": "root"}, latest.Data["data"])
+	assert.Equal(t, map[string]interface{}{"user": "admin"}, first.Data["data"])
+	assert.Equal(t, []interface{}{"db"}, list.Data["keys"])
+	assert.Equal(t, "secret/", mount.Data["path"])
+	assert.Equal(t, map[string]interface{}{"user": "root"}, v.Secret("secret/app/db"))
+}
+
+func TestVault_KV2_CAS(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.WriteSecret("secret/app", map[string]interface{}{"user": "admin"})
+	cli := newTestClient(t, v, v.RootToken())
+
+	// make test
+	_, err := cli.Logical().Write("secret/data/app", map[string]interface{}{
+		"data":    map[string]interface{}{"user": "root"},
+		"options": map[string]interface{}{"cas": 0},
+	})
+
+	// assertions
+	respErr, ok := err.(*api.ResponseError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, respErr.StatusCode)
+	assert.Equal(t, map[string]interface{}{"user": "admin"}, v.Secret("secret/app"))
+}
+
+func TestVault_KV2_Patch(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.WriteSecret("secret/app", map[string]interface{}{"user": "admin", "password": "pass"})
+	cli := newTestClient(t, v, v.RootToken())
+	body := map[string]interface{}{"data": map[string]interface{}{"password": nil, "host": "db"}}
+
+	// make test
+	req := cli.NewRequest(http.MethodPatch, "/v1/secret/data/app")
+	_ = req.SetJSONBody(body)
+	_, rawErr := cli.RawRequest(req)
+	_, patchErr := cli.Logical().JSONMergePatch(context.Background(), "secret/data/app", body)
+
+	// assertions
+	respErr, ok := rawErr.(*api.ResponseError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusUnsupportedMediaType, respErr.StatusCode)
+	assert.Nil(t, patchErr)
+	assert.Equal(t, map[string]interface{}{"user": "admin", "host": "db"}, v.Secret("secret/app"))
+}
+
+func TestVault_KV2_Delete(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.WriteSecret("secret/app", map[string]interface{}{"user": "admin"})
+	cli := newTestClient(t, v, v.RootToken())
+
+	// make test
+	v.DeleteSecret("secret/app")
+	secret, err := cli.Logical().Read("secret/data/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.NotNil(t, secret)
+	assert.Nil(t, secret.Data["data"])
+	assert.Nil(t, v.Secret("secret/app"))
+}
+
+func TestVault_KV1(t *testing.T) {
+	// prepare
+	v := NewVault(t, WithKVMount("kv", 1))
+	cli := newTestClient(t, v, v.RootToken())
+
+	// make test
+	_, writeErr := cli.Logical().Write("kv/app", map[string]interface{}{"host": "db"})
+	secret, readErr := cli.Logical().Read("kv/app")
+	missing, missingErr := cli.Logical().Read("kv/missing")
+
+	// assertions
+	assert.Nil(t, writeErr)
+	assert.Nil(t, readErr)
+	assert.Nil(t, missingErr)
+	assert.Nil(t, missing)
+	assert.Equal(t, map[string]interface{}{"host": "db"}, secret.Data)
+}
+
+func TestVault_WriteSecret_Panics_NoMount(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+
+	// assertions
+	assert.Panics(t, func() {
+		v.WriteSecret("unknown/app", map[string]interface{}{})
+	})
+}
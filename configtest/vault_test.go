@@ -0,0 +1,105 @@
+package configtest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T, v *Vault, token string) *api.Client {
+	cfg := api.DefaultConfig()
+	cfg.Address = v.URL()
+	cfg.MaxRetries = 0
+	cli, err := api.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.SetToken(token)
+	return cli
+}
+
+func TestVault_Health(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	cli := newTestClient(t, v, "")
+
+	// make test
+	health, err := cli.Sys().Health()
+
+	// assertions
+	assert.Nil(t, err)
+	assert.True(t, health.Initialized)
+	assert.False(t, health.Sealed)
+}
+
+func TestVault_PermissionDenied(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	cli := newTestClient(t, v, "unknown")
+
+	// make test
+	_, err := cli.Logical().Read("secret/data/app")
+
+	// assertions
+	respErr, ok := err.(*api.ResponseError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, respErr.StatusCode)
+}
+
+func TestVault_FailNext(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.WriteSecret("secret/app", map[string]interface{}{"key": "value"})
+	v.FailNext(2, http.StatusBadGateway)
+	cli := newTestClient(t, v, v.RootToken())
+
+	// make test
+	_, firstErr := cli.Logical().Read("secret/data/app")
+	_, secondErr := cli.Logical().Read("secret/data/app")
+	secret, thirdErr := cli.Logical().Read("secret/data/app")
+
+	// assertions
+	assert.NotNil(t, firstErr)
+	assert.NotNil(t, secondErr)
+	assert.Nil(t, thirdErr)
+	assert.NotNil(t, secret)
+	assert.Equal(t, 3, v.Requests())
+}
+
+func TestVault_Sealed(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.WriteSecret("secret/app", map[string]interface{}{"key": "value"})
+	cli := newTestClient(t, v, v.RootToken())
+
+	// make test
+	v.Seal()
+	_, sealedErr := cli.Logical().Read("secret/data/app")
+	health, _ := cli.Sys().Health()
+	v.Unseal()
+	_, unsealedErr := cli.Logical().Read("secret/data/app")
+
+	// assertions
+	respErr, ok := sealedErr.(*api.ResponseError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, respErr.StatusCode)
+	assert.True(t, health.Sealed)
+	assert.Nil(t, unsealedErr)
+}
+
+func TestVault_Latency(t *testing.T) {
+	// prepare
+	v := NewVault(t)
+	v.SetLatency(100 * time.Millisecond)
+	cli := newTestClient(t, v, v.RootToken())
+	cli.SetClientTimeout(20 * time.Millisecond)
+
+	// make test
+	_, err := cli.Logical().Read("secret/data/app")
+
+	// assertions
+	assert.NotNil(t, err)
+}
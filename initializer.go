@@ -1,41 +1,133 @@
 package config
 
-import "io"
-
-type configOption func(cfg interface{}) error
+import (
+	"context"
+	"io"
+	"strings"
+)
 
 // WithParsingBytes initialize option with passing bytes for unmarshalling based on fileType
-func WithParsingBytes(data []byte, fileType FileType) configOption {
-	return func(cfg interface{}) error {
+func WithParsingBytes(data []byte, fileType FileType) Source {
+	return NewSource("bytes", func(ctx context.Context, cfg interface{}) error {
 		return ParseBytes(data, fileType, cfg)
-	}
+	})
 }
 
 // WithParsingReader initialize option with passing io.Reader for unmarshalling based on fileType
-func WithParsingReader(reader io.Reader, fileType FileType) configOption {
-	return func(cfg interface{}) error {
-		return ParseReader(reader, fileType, cfg)
-	}
+func WithParsingReader(reader io.Reader, fileType FileType) Source {
+	return NewSource("reader", func(ctx context.Context, cfg interface{}) error {
+		return ParseReaderCtx(ctx, reader, fileType, cfg)
+	})
 }
 
 // WithParsingFile initialize option passed to config file for it's opening and unmarshalling based on fileType
-func WithParsingFile(filePath string, fileType FileType) configOption {
-	return func(cfg interface{}) error {
-		return ParseFile(filePath, fileType, cfg)
-	}
+// Source could be watched for changes, see Reloader.WatchSources
+func WithParsingFile(filePath string, fileType FileType) Source {
+	return &fileSource{path: filePath, fileType: fileType, interval: defaultFileWatchInterval}
 }
 
 // WithParsingEnv initialize option for parsing ENV
-func WithParsingEnv() configOption {
-	return func(cfg interface{}) error {
+func WithParsingEnv() Source {
+	return NewSource("env", func(ctx context.Context, cfg interface{}) error {
 		return ParseEnv(cfg)
-	}
+	})
+}
+
+// WithParsingVault initialize option for parsing fields tagged with govault, tags without path are read from path
+// VaultClient is created from Environment, see NewVaultClient
+func WithParsingVault(path string) Source {
+	return NewSource("vault "+path, func(ctx context.Context, cfg interface{}) error {
+		cli, err := NewVaultClient()
+		if err != nil {
+			return err
+		}
+		return vaultNotFound(markSecretChanges(ctx, cfg, func() error {
+			return ParseVaultCtx(ctx, cli, path, cfg)
+		}))
+	})
+}
+
+// WithParsingVaultClient initialize option for parsing fields tagged with govault using configured VaultClient
+func WithParsingVaultClient(cli *VaultClient, path string) Source {
+	return NewSource("vault "+path, func(ctx context.Context, cfg interface{}) error {
+		return vaultNotFound(markSecretChanges(ctx, cfg, func() error {
+			return ParseVaultCtx(ctx, cli, path, cfg)
+		}))
+	})
+}
+
+// WithParsingVaultPaths initialize option for merging KV secret data stored under paths into config,
+// paths are read concurrently and merged in passed order, so later paths override keys of earlier ones
+func WithParsingVaultPaths(cli *VaultClient, paths ...string) Source {
+	return NewSource("vault "+strings.Join(paths, ", "), func(ctx context.Context, cfg interface{}) error {
+		return vaultNotFound(markSecretChanges(ctx, cfg, func() error {
+			return cli.ReadBatchCtx(ctx, paths, 0).Merge(cfg)
+		}))
+	})
+}
+
+// WithTransitDecryption initialize option for decrypting Vault Transit ciphertext in values parsed by previous options
+func WithTransitDecryption(cli *VaultClient, opts ...transitOption) Source {
+	return NewSource("vault transit", func(ctx context.Context, cfg interface{}) error {
+		return DecryptTransitCtx(ctx, cli, cfg, opts...)
+	})
+}
+
+// WithParsingVaultTree initialize option for merging all KV secrets stored under prefix into config, see VaultClient.ReadTree
+func WithParsingVaultTree(cli *VaultClient, prefix string, opts ...treeOption) Source {
+	return NewSource("vault tree "+prefix, func(ctx context.Context, cfg interface{}) error {
+		return vaultNotFound(markSecretChanges(ctx, cfg, func() error {
+			return cli.ReadTreeIntoCtx(ctx, prefix, cfg, opts...)
+		}))
+	})
+}
+
+// WithParsingVaultWrapped initialize option for unwrapping response-wrapped KV secret into config,
+// wrapping token is single-use, so the option could be applied only once
+func WithParsingVaultWrapped(cli *VaultClient, wrappingToken string, allowedPaths ...string) Source {
+	return NewSource("vault wrapped secret", func(ctx context.Context, cfg interface{}) error {
+		return markSecretChanges(ctx, cfg, func() error {
+			return cli.UnwrapIntoCtx(ctx, wrappingToken, cfg, allowedPaths...)
+		})
+	})
+}
+
+// WithSeedingVault initialize option for writing values of govault tagged fields parsed by previous options to Vault,
+// see SeedVault
+func WithSeedingVault(cli *VaultClient, defaultPath string) Source {
+	return NewSource("vault seed "+defaultPath, func(ctx context.Context, cfg interface{}) error {
+		return SeedVaultCtx(ctx, cli, defaultPath, cfg)
+	})
+}
+
+// WithResolvingReferences initialize option for replacing vault://, env://, file:// and ${scheme:reference} references
+// in values parsed by previous options, passed resolvers override default ones by scheme
+func WithResolvingReferences(resolvers ...Resolver) Source {
+	return NewSource("references", func(ctx context.Context, cfg interface{}) error {
+		return ResolveReferencesCtx(ctx, cfg, resolvers...)
+	})
 }
 
 // NewConfig initializing cfg struct with various of options
-func NewConfig(cfg interface{}, opts ...configOption) (err error) {
+func NewConfig(cfg interface{}, opts ...Source) (err error) {
+	return NewConfigCtx(context.Background(), cfg, opts...)
+}
+
+/*
+NewConfigCtx initializing cfg struct with various of options like NewConfig
+ctx cancellation and deadline are passed to Vault requests and readers, options are not applied after ctx is done
+
+Example:
+ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+defer cancel()
+err := NewConfigCtx(ctx, cfg, WithParsingFile("config.yaml", YAML), WithParsingVault("secret/app"))
+*/
+func NewConfigCtx(ctx context.Context, cfg interface{}, opts ...Source) (err error) {
 	for _, v := range opts {
-		err = v(cfg)
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		err = v.Load(ctx, cfg)
 		if err != nil {
 			return
 		}
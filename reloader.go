@@ -0,0 +1,382 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	secretTag = "gosecret"
+
+	defaultReloadDebounce = 500 * time.Millisecond
+	redactedValue         = "[REDACTED]"
+)
+
+// ReloadStatus describes the outcome of the last reload attempt
+type ReloadStatus struct {
+	// Time when the reload finished
+	Time time.Time
+	// Err is not nil if the reload failed, in this case config stays untouched
+	Err error
+	// Changed contains paths of the fields which were changed by reload, e.g. "InnerThird.FirstInner"
+	Changed []string
+}
+
+/*
+Reloader keeps cfg in sync with the options it was created with.
+Every reload starts from a copy of cfg taken before the first load, applies all options to it
+and only if all of them succeed replaces the content of cfg.
+
+Important note: cfg is updated in place, use RLock/RUnlock to read it while reloads could happen.
+Options created with WithParsingReader can be applied only once, use WithParsingFile or WithParsingBytes instead
+
+Example:
+r, err := NewReloader(cfg, WithParsingFile("config.yaml", YAML), WithParsingEnv())
+go r.WatchSignal(ctx) // kill -HUP <pid> reloads config
+*/
+type Reloader struct {
+	// Debounce is the time to wait after the last signal before reloading, bursts of signals cause one reload
+	Debounce time.Duration
+	// Logger receives changed field paths, values of fields tagged with gosecret:"true", govault or gotransit,
+	// values loaded by Vault sources and values written by resolvers or Transit decryption are redacted
+	Logger *log.Logger
+
+	mu       sync.RWMutex
+	cfg      interface{}
+	template interface{}
+	opts     []Source
+	// secrets holds paths of fields written by resolvers or Transit decryption during the last load
+	secrets *secretPaths
+
+	statusMu sync.Mutex
+	status   ReloadStatus
+}
+
+// NewReloader initializing cfg struct with various of options like NewConfig and returns Reloader for further reloads
+// cfg should be passed as pointer
+func NewReloader(cfg interface{}, opts ...Source) (*Reloader, error) {
+	return NewReloaderCtx(context.Background(), cfg, opts...)
+}
+
+// NewReloaderCtx creates Reloader like NewReloader, ctx is used only for the first load
+func NewReloaderCtx(ctx context.Context, cfg interface{}, opts ...Source) (*Reloader, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, fmt.Errorf("cfg should be a non nil pointer, got %T", cfg)
+	}
+	r := &Reloader{
+		Debounce: defaultReloadDebounce,
+		Logger:   log.New(os.Stderr, "go-config: ", log.LstdFlags),
+		cfg:      cfg,
+		template: cloneValue(v).Interface(),
+		opts:     opts,
+	}
+	ctx, r.secrets = withSecretPaths(ctx)
+	if err := NewConfigCtx(ctx, cfg, opts...); err != nil {
+		return nil, err
+	}
+	r.setStatus(ReloadStatus{Time: time.Now()})
+	return r, nil
+}
+
+// Reload applies options to a fresh copy of config and replaces cfg content with it if succeed
+// Result of the reload could be fetched with Status method
+func (r *Reloader) Reload() error {
+	return r.ReloadCtx(context.Background())
+}
+
+// ReloadCtx reloads config like Reload, if ctx is done before all options are applied cfg stays untouched
+func (r *Reloader) ReloadCtx(ctx context.Context) error {
+	fresh := cloneValue(reflect.ValueOf(r.template))
+
+	ctx, secrets := withSecretPaths(ctx)
+	if err := NewConfigCtx(ctx, fresh.Interface(), r.opts...); err != nil {
+		r.setStatus(ReloadStatus{Time: time.Now(), Err: err})
+		if r.Logger != nil {
+			r.Logger.Printf("reload failed: %v", err)
+		}
+		return err
+	}
+
+	r.mu.Lock()
+	current := reflect.ValueOf(r.cfg)
+	changes := diffValues(current.Elem(), fresh.Elem(), "", false)
+	for i, c := range changes {
+		// secret value could be written either in the previous load or in this one
+		if r.secrets.covers(c.path) || secrets.covers(c.path) {
+			changes[i].from, changes[i].to = redactedValue, redactedValue
+		}
+	}
+	current.Elem().Set(fresh.Elem())
+	r.secrets = secrets
+	r.mu.Unlock()
+
+	changed := make([]string, 0, len(changes))
+	for _, c := range changes {
+		changed = append(changed, c.path)
+		if r.Logger != nil {
+			r.Logger.Printf("reload: %s changed from %s to %s", c.path, c.from, c.to)
+		}
+	}
+	r.setStatus(ReloadStatus{Time: time.Now(), Changed: changed})
+	return nil
+}
+
+// WatchSignal reloads config each time when process receives one of sig (SIGHUP if sig is not passed)
+// Blocks until ctx is done, reload in progress is cancelled with ctx
+func (r *Reloader) WatchSignal(ctx context.Context, sig ...os.Signal) error {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig...)
+	defer signal.Stop(signals)
+
+	return r.watch(ctx, signals)
+}
+
+/*
+WatchSources reloads config each time one of sources implementing WatchableSource reports a change,
+e.g. file passed with WithParsingFile is modified, bursts of changes cause one reload like in WatchSignal
+Blocks until ctx is done, returns error immediately if none of sources could be watched
+
+Example:
+r, err := NewReloader(cfg, WithParsingFile("config.yaml", YAML), WithParsingEnv())
+go r.WatchSources(ctx)
+*/
+func (r *Reloader) WatchSources(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes := make(chan os.Signal, 1)
+	watched := 0
+	for _, src := range r.opts {
+		w, ok := src.(WatchableSource)
+		if !ok {
+			continue
+		}
+		watched++
+		go func(w WatchableSource) {
+			err := w.Watch(ctx, func() {
+				select {
+				case changes <- sourceChanged(w.Name()):
+				default:
+				}
+			})
+			if err != nil && ctx.Err() == nil && r.Logger != nil {
+				r.Logger.Printf("watching %s failed: %v", w.Name(), err)
+			}
+		}(w)
+	}
+	if watched == 0 {
+		return errors.New("none of sources could be watched")
+	}
+	return r.watch(ctx, changes)
+}
+
+// sourceChanged is passed to watch loop like a signal when watched source is changed
+type sourceChanged string
+
+func (s sourceChanged) String() string {
+	return string(s) + " changed"
+}
+
+func (s sourceChanged) Signal() {}
+
+// Status returns outcome of the last load or reload
+func (r *Reloader) Status() ReloadStatus {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	return r.status
+}
+
+// RLock locks cfg for reading, reloads wait until RUnlock is called
+func (r *Reloader) RLock() {
+	r.mu.RLock()
+}
+
+// RUnlock undoes a single RLock call
+func (r *Reloader) RUnlock() {
+	r.mu.RUnlock()
+}
+
+func (r *Reloader) watch(ctx context.Context, signals <-chan os.Signal) error {
+	var timer *time.Timer
+	var fire <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		case <-signals:
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.NewTimer(r.Debounce)
+			fire = timer.C
+		case <-fire:
+			timer, fire = nil, nil
+			_ = r.ReloadCtx(ctx)
+		}
+	}
+}
+
+func (r *Reloader) setStatus(status ReloadStatus) {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	r.status = status
+}
+
+type fieldChange struct {
+	path, from, to string
+}
+
+func diffValues(from, to reflect.Value, path string, secret bool) []fieldChange {
+	if from.Kind() == reflect.Ptr && to.Kind() == reflect.Ptr && !from.IsNil() && !to.IsNil() {
+		return diffValues(from.Elem(), to.Elem(), path, secret)
+	}
+	if from.Kind() == reflect.Struct {
+		var changes []fieldChange
+		t := from.Type()
+		for i := 0; i < from.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldPath := field.Name
+			if path != "" {
+				fieldPath = path + "." + field.Name
+			}
+			changes = append(changes, diffValues(from.Field(i), to.Field(i), fieldPath, secret || isSecretField(field))...)
+		}
+		return changes
+	}
+	if reflect.DeepEqual(from.Interface(), to.Interface()) {
+		return nil
+	}
+	change := fieldChange{path: path, from: redactedValue, to: redactedValue}
+	if !secret {
+		change.from = fmt.Sprintf("%#v", from.Interface())
+		change.to = fmt.Sprintf("%#v", to.Interface())
+	}
+	return []fieldChange{change}
+}
+
+func isSecretField(field reflect.StructField) bool {
+	return field.Tag.Get(secretTag) == "true" || field.Tag.Get(vaultTag) != "" || field.Tag.Get(transitTag) != ""
+}
+
+// secretPathsKey is context key of secretPaths collected while options are applied
+type secretPathsKey struct{}
+
+// secretPaths collects paths of fields which values were written by resolvers or decrypted, Reloader never logs them
+type secretPaths struct {
+	mu    sync.Mutex
+	paths map[string]bool
+}
+
+func withSecretPaths(ctx context.Context) (context.Context, *secretPaths) {
+	s := &secretPaths{paths: map[string]bool{}}
+	return context.WithValue(ctx, secretPathsKey{}, s), s
+}
+
+// markSecretPath records path of the field holding secret value if ctx is passed by Reloader
+func markSecretPath(ctx context.Context, path string) {
+	s, ok := ctx.Value(secretPathsKey{}).(*secretPaths)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths[path] = true
+}
+
+// markSecretChanges loads cfg with load and marks paths of all fields it changed as secret if ctx is passed by Reloader,
+// it is used by sources which values are secret regardless of field tags, e.g. data read from Vault
+func markSecretChanges(ctx context.Context, cfg interface{}, load func() error) error {
+	v := reflect.ValueOf(cfg)
+	if _, ok := ctx.Value(secretPathsKey{}).(*secretPaths); !ok || v.Kind() != reflect.Ptr || v.IsNil() {
+		return load()
+	}
+	before := cloneValue(v)
+	err := load()
+	for _, c := range diffValues(before.Elem(), v.Elem(), "", false) {
+		markSecretPath(ctx, c.path)
+	}
+	return err
+}
+
+// covers reports whether value at path is secret, contains secret (e.g. element of slice or map at path)
+// or is a part of secret value, e.g. field of struct which was set as a whole
+func (s *secretPaths) covers(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for p := range s.paths {
+		if p == path || strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") ||
+			strings.HasPrefix(path, p+".") || strings.HasPrefix(path, p+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(cloneValue(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), cloneValue(iter.Value()))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cloneValue(v.Elem()))
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vielendanke/go-config/configtest"
+)
+
+type reloadTestConfig struct {
+	Host     string           `json:"host" goenv:"RELOAD_HOST"`
+	Password string           `json:"password" gosecret:"true"`
+	Inner    *reloadTestInner `json:"inner"`
+}
+
+type reloadTestInner struct {
+	Port int `json:"port"`
+}
+
+type reloadRefTestConfig struct {
+	Host   string           `json:"host"`
+	Inner  *reloadTestInner `json:"inner"`
+	Tokens []string         `json:"tokens"`
+}
+
+func writeReloadFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestReloader fails test if the first load fails, logging is disabled
+func newTestReloader(t *testing.T, cfg interface{}, opts ...Source) *Reloader {
+	r, err := NewReloader(cfg, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Logger = nil
+	return r
+}
+
+func TestReloader_Reload(t *testing.T) {
+	// prepare
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeReloadFile(t, path, `{"host":"first","password":"old","inner":{"port":1}}`)
+	cfg := &reloadTestConfig{Inner: &reloadTestInner{}}
+	logs := &bytes.Buffer{}
+
+	r := newTestReloader(t, cfg, WithParsingFile(path, JSON))
+	r.Logger = log.New(logs, "", 0)
+	writeReloadFile(t, path, `{"host":"second","password":"new","inner":{"port":1}}`)
+
+	// make test
+	reloadErr := r.Reload()
+
+	// assertions
+	assert.Nil(t, reloadErr)
+	assert.Equal(t, "second", cfg.Host)
+	assert.Equal(t, "new", cfg.Password)
+	assert.Equal(t, 1, cfg.Inner.Port)
+	assert.Equal(t, []string{"Host", "Password"}, r.Status().Changed)
+	assert.Nil(t, r.Status().Err)
+	assert.Contains(t, logs.String(), `Host changed from "first" to "second"`)
+	assert.Contains(t, logs.String(), "Password changed from [REDACTED] to [REDACTED]")
+	assert.NotContains(t, logs.String(), "new")
+}
+
+func TestReloader_Reload_EnvRemoved(t *testing.T) {
+	// prepare
+	os.Setenv("RELOAD_HOST", "env")
+	cfg := &reloadTestConfig{Host: "default", Inner: &reloadTestInner{}}
+	r := newTestReloader(t, cfg, WithParsingEnv())
+	hostAfterLoad := cfg.Host
+	os.Unsetenv("RELOAD_HOST")
+
+	// make test
+	reloadErr := r.Reload()
+
+	// assertions
+	assert.Nil(t, reloadErr)
+	assert.Equal(t, "env", hostAfterLoad)
+	assert.Equal(t, "default", cfg.Host)
+}
+
+func TestReloader_Reload_FailsKeepsConfig(t *testing.T) {
+	// prepare
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeReloadFile(t, path, `{"host":"first","inner":{"port":1}}`)
+	cfg := &reloadTestConfig{Inner: &reloadTestInner{}}
+	r := newTestReloader(t, cfg, WithParsingFile(path, JSON))
+	writeReloadFile(t, path, `{"host":`)
+
+	// make test
+	reloadErr := r.Reload()
+
+	// assertions
+	assert.NotNil(t, reloadErr)
+	assert.Equal(t, "first", cfg.Host)
+	assert.Equal(t, reloadErr, r.Status().Err)
+	assert.False(t, r.Status().Time.IsZero())
+}
+
+func TestReloader_Watch_DebouncesSignals(t *testing.T) {
+	// prepare
+	loads := 0
+	cfg := &reloadTestConfig{Inner: &reloadTestInner{}}
+	r := newTestReloader(t, cfg, NewSource("counter", func(_ context.Context, cfg interface{}) error {
+		loads++
+		return nil
+	}))
+	r.Debounce = 50 * time.Millisecond
+	signals := make(chan os.Signal, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	// make test
+	go func() {
+		done <- r.watch(ctx, signals)
+	}()
+	for i := 0; i < 3; i++ {
+		signals <- os.Interrupt
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	watchErr := <-done
+
+	// assertions
+	assert.Equal(t, 2, loads)
+	assert.True(t, errors.Is(watchErr, context.Canceled))
+}
+
+func TestNewReloader_Fails_NotPointer(t *testing.T) {
+	// make test
+	r, err := NewReloader(reloadTestConfig{})
+
+	// assertions
+	assert.Nil(t, r)
+	assert.NotNil(t, err)
+}
+
+func TestReloader_WatchSources(t *testing.T) {
+	// prepare
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeReloadFile(t, path, `{"host":"first","inner":{"port":1}}`)
+	cfg := &reloadTestConfig{Inner: &reloadTestInner{}}
+	file := WithParsingFile(path, JSON)
+	file.(*fileSource).interval = 10 * time.Millisecond
+	r := newTestReloader(t, cfg, file)
+	r.Debounce = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	// make test
+	go func() {
+		done <- r.WatchSources(ctx)
+	}()
+	time.Sleep(30 * time.Millisecond)
+	writeReloadFile(t, path, `{"host":"second-host","inner":{"port":1}}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for r.Status().Changed == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	// assertions
+	assert.True(t, errors.Is(<-done, context.Canceled))
+	r.RLock()
+	assert.Equal(t, "second-host", cfg.Host)
+	r.RUnlock()
+	assert.Equal(t, []string{"Host"}, r.Status().Changed)
+}
+
+func TestReloader_WatchSources_Fails_NothingToWatch(t *testing.T) {
+	// prepare
+	r := newTestReloader(t, &reloadTestConfig{Inner: &reloadTestInner{}}, WithParsingEnv())
+
+	// make test
+	err := r.WatchSources(context.Background())
+
+	// assertions
+	assert.NotNil(t, err)
+}
+
+func TestReloader_Reload_RedactsResolvedReferences(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteSecret("secret/db", map[string]interface{}{"password": "s3cr3t-one"})
+	cli, cliErr := NewVaultClient(WithVaultAddress(vault.URL()), WithVaultToken(vault.RootToken()))
+	t.Setenv("RELOAD_TOKEN", "t0ken-one")
+	data := []byte(`{"host":"vault://secret/db#password","inner":{"port":1},"tokens":["env://RELOAD_TOKEN"]}`)
+	cfg := &reloadRefTestConfig{}
+	logs := &bytes.Buffer{}
+	r := newTestReloader(t, cfg, WithParsingBytes(data, JSON), WithResolvingReferences(&VaultResolver{Client: cli}))
+	r.Logger = log.New(logs, "", 0)
+	vault.WriteSecret("secret/db", map[string]interface{}{"password": "s3cr3t-two"})
+	t.Setenv("RELOAD_TOKEN", "t0ken-two")
+
+	// make test
+	reloadErr := r.Reload()
+
+	// assertions
+	assert.Nil(t, cliErr)
+	assert.Nil(t, reloadErr)
+	assert.Equal(t, "s3cr3t-two", cfg.Host)
+	assert.Equal(t, []string{"t0ken-two"}, cfg.Tokens)
+	assert.Equal(t, []string{"Host", "Tokens"}, r.Status().Changed)
+	assert.Contains(t, logs.String(), "Host changed from [REDACTED] to [REDACTED]")
+	assert.Contains(t, logs.String(), "Tokens changed from [REDACTED] to [REDACTED]")
+	assert.NotContains(t, logs.String(), "s3cr3t")
+	assert.NotContains(t, logs.String(), "t0ken")
+}
+
+func TestReloader_Reload_RedactsRemovedReference(t *testing.T) {
+	// prepare
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("RELOAD_HOST_SECRET", "s3cr3t")
+	writeReloadFile(t, path, `{"host":"env://RELOAD_HOST_SECRET","inner":{"port":1}}`)
+	cfg := &reloadTestConfig{Inner: &reloadTestInner{}}
+	logs := &bytes.Buffer{}
+	r := newTestReloader(t, cfg, WithParsingFile(path, JSON), WithResolvingReferences())
+	r.Logger = log.New(logs, "", 0)
+	writeReloadFile(t, path, `{"host":"plain","inner":{"port":1}}`)
+
+	// make test
+	reloadErr := r.Reload()
+
+	// assertions
+	assert.Nil(t, reloadErr)
+	assert.Equal(t, "plain", cfg.Host)
+	assert.Contains(t, logs.String(), "Host changed from [REDACTED] to [REDACTED]")
+	assert.NotContains(t, logs.String(), "s3cr3t")
+}
+
+func TestReloader_Reload_RedactsVaultSources(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteSecret("secret/app", map[string]interface{}{"host": "s3cret-old"})
+	vault.WriteSecret("secret/tree/inner", map[string]interface{}{"port": 1111})
+	cli := newFakeVaultClient(t, vault)
+	cfg := &reloadRefTestConfig{}
+	logs := &bytes.Buffer{}
+	r := newTestReloader(t, cfg, WithParsingVaultPaths(cli, "secret/app"), WithParsingVaultTree(cli, "secret/tree"))
+	r.Logger = log.New(logs, "", 0)
+	vault.WriteSecret("secret/app", map[string]interface{}{"host": "s3cret-new"})
+	vault.WriteSecret("secret/tree/inner", map[string]interface{}{"port": 2222})
+
+	// make test
+	reloadErr := r.Reload()
+
+	// assertions
+	assert.Nil(t, reloadErr)
+	assert.Equal(t, "s3cret-new", cfg.Host)
+	assert.Equal(t, 2222, cfg.Inner.Port)
+	assert.Equal(t, []string{"Host", "Inner.Port"}, r.Status().Changed)
+	assert.Contains(t, logs.String(), "Host changed from [REDACTED] to [REDACTED]")
+	assert.Contains(t, logs.String(), "Inner.Port changed from [REDACTED] to [REDACTED]")
+	assert.NotContains(t, logs.String(), "s3cret")
+	assert.NotContains(t, logs.String(), "1111")
+	assert.NotContains(t, logs.String(), "2222")
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// referencePattern matches ${scheme:reference} placeholders which could be embedded into a string
+var referencePattern = regexp.MustCompile(`\$\{([a-zA-Z][a-zA-Z0-9+.-]*):([^}]*)\}`)
+
+/*
+Resolver resolves references of a single scheme, e.g. "vault" for vault://secret/data/db#password
+Resolve receives references without scheme prefix and duplicates, and returns resolved value for each of them
+If a single reference fails, Resolver should return ReferenceError with Ref in scheme://reference form
+*/
+type Resolver interface {
+	Scheme() string
+	Resolve(ctx context.Context, refs []string) (map[string]string, error)
+}
+
+// ReferenceError describes reference which could not be resolved, Path is a path of the field which held the reference
+type ReferenceError struct {
+	Ref  string
+	Path string
+	Err  error
+}
+
+func (e *ReferenceError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("reference %s: %v", e.Ref, e.Err)
+	}
+	return fmt.Sprintf("reference %s at %s: %v", e.Ref, e.Path, e.Err)
+}
+
+func (e *ReferenceError) Unwrap() error {
+	return e.Err
+}
+
+// EnvResolver resolves env://NAME and ${env:NAME} references from Environment
+type EnvResolver struct{}
+
+// Scheme returns "env"
+func (EnvResolver) Scheme() string {
+	return "env"
+}
+
+// Resolve looks up Environment variables, not set variable is an error
+func (EnvResolver) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	values := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		value, ok := os.LookupEnv(ref)
+		if !ok {
+			return nil, &ReferenceError{Ref: "env://" + ref, Err: errors.New("environment variable is not set")}
+		}
+		values[ref] = value
+	}
+	return values, nil
+}
+
+// FileResolver resolves file:///path and ${file:/path} references with file content, trailing new line is trimmed
+type FileResolver struct{}
+
+// Scheme returns "file"
+func (FileResolver) Scheme() string {
+	return "file"
+}
+
+// Resolve reads files
+func (FileResolver) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	values := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return nil, &ReferenceError{Ref: "file://" + ref, Err: err}
+		}
+		values[ref] = strings.TrimRight(string(data), "\r\n")
+	}
+	return values, nil
+}
+
+/*
+ResolveReferences runs over string values of cfg (struct fields, slices, maps and interface values)
+and replaces references with values returned by resolvers
+Reference could be a whole value "scheme://reference" or embedded placeholder "${scheme:reference}"
+Resolvers for env, file and vault schemes are used by default, passed resolvers override them by scheme
+Default vault resolver creates VaultClient from Environment only if vault references are found
+Resolved values are redacted by Reloader, so it never logs them
+
+Example:
+password: vault://secret/data/db#password
+url: postgres://${env:DB_HOST}:5432/app
+key: file:///run/secrets/key
+*/
+func ResolveReferences(cfg interface{}, resolvers ...Resolver) error {
+	return ResolveReferencesCtx(context.Background(), cfg, resolvers...)
+}
+
+type referenceSlot struct {
+	path  string
+	value reflect.Value
+	set   func(reflect.Value)
+	// tag of the struct field holding the value, elements of slices and maps inherit tag of their field
+	tag reflect.StructTag
+}
+
+// ResolveReferencesCtx replaces references like ResolveReferences, ctx is passed to resolvers
+func ResolveReferencesCtx(ctx context.Context, cfg interface{}, resolvers ...Resolver) error {
+	if cfg == nil {
+		return nil
+	}
+	byScheme := map[string]Resolver{}
+	for _, r := range append([]Resolver{EnvResolver{}, FileResolver{}, &VaultResolver{}}, resolvers...) {
+		byScheme[r.Scheme()] = r
+	}
+
+	var slots []referenceSlot
+	collectReferenceSlots(reflect.ValueOf(cfg), "", "", nil, &slots)
+
+	refs := map[string]map[string]string{}
+	paths := map[string]string{}
+	for _, slot := range slots {
+		for _, ref := range findReferences(slot.value.String(), byScheme) {
+			if refs[ref.scheme] == nil {
+				refs[ref.scheme] = map[string]string{}
+			}
+			refs[ref.scheme][ref.body] = ""
+			canonical := ref.canonical()
+			if _, ok := paths[canonical]; !ok {
+				paths[canonical] = slot.path
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	schemes := make([]string, 0, len(refs))
+	for scheme := range refs {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	for _, scheme := range schemes {
+		bodies := make([]string, 0, len(refs[scheme]))
+		for body := range refs[scheme] {
+			bodies = append(bodies, body)
+		}
+		sort.Strings(bodies)
+		values, err := byScheme[scheme].Resolve(ctx, bodies)
+		if err != nil {
+			var refErr *ReferenceError
+			if errors.As(err, &refErr) && refErr.Path == "" {
+				refErr.Path = paths[refErr.Ref]
+			}
+			return err
+		}
+		for _, body := range bodies {
+			value, ok := values[body]
+			if !ok {
+				canonical := reference{scheme: scheme, body: body}.canonical()
+				return &ReferenceError{Ref: canonical, Path: paths[canonical], Err: errors.New("resolver returned no value")}
+			}
+			refs[scheme][body] = value
+		}
+	}
+
+	for _, slot := range slots {
+		if len(findReferences(slot.value.String(), byScheme)) == 0 {
+			continue
+		}
+		markSecretPath(ctx, slot.path)
+		resolved := replaceReferences(slot.value.String(), byScheme, refs)
+		slot.set(reflect.ValueOf(resolved).Convert(slot.value.Type()))
+	}
+	return nil
+}
+
+// collectReferenceSlots finds settable string values, set assigns a new value to the place where v is stored
+func collectReferenceSlots(v reflect.Value, path string, tag reflect.StructTag, set func(reflect.Value), slots *[]referenceSlot) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			el := v.Elem()
+			collectReferenceSlots(el, path, tag, settable(el), slots)
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			collectReferenceSlots(v.Elem(), path, tag, set, slots)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			collectReferenceSlots(v.Field(i), joinKeyPath(path, t.Field(i).Name), t.Field(i).Tag, settable(v.Field(i)), slots)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectReferenceSlots(v.Index(i), fmt.Sprintf("%s[%d]", path, i), tag, settable(v.Index(i)), slots)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			collectReferenceSlots(iter.Value(), joinKeyPath(path, fmt.Sprint(key.Interface())), tag, func(nv reflect.Value) {
+				v.SetMapIndex(key, nv)
+			}, slots)
+		}
+	case reflect.String:
+		if set != nil {
+			*slots = append(*slots, referenceSlot{path: path, value: v, set: set, tag: tag})
+		}
+	}
+}
+
+func settable(v reflect.Value) func(reflect.Value) {
+	if !v.CanSet() {
+		return nil
+	}
+	return v.Set
+}
+
+func joinKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+type reference struct {
+	scheme, body string
+}
+
+// canonical returns reference in scheme://reference form, resolvers use it in ReferenceError
+func (r reference) canonical() string {
+	return r.scheme + "://" + r.body
+}
+
+// findReferences returns references of known schemes found in value
+func findReferences(value string, resolvers map[string]Resolver) []reference {
+	if ref, ok := wholeReference(value, resolvers); ok {
+		return []reference{ref}
+	}
+	var refs []reference
+	for _, m := range referencePattern.FindAllStringSubmatch(value, -1) {
+		if _, ok := resolvers[m[1]]; ok {
+			refs = append(refs, reference{scheme: m[1], body: m[2]})
+		}
+	}
+	return refs
+}
+
+func wholeReference(value string, resolvers map[string]Resolver) (reference, bool) {
+	idx := strings.Index(value, "://")
+	if idx <= 0 {
+		return reference{}, false
+	}
+	scheme := value[:idx]
+	if _, ok := resolvers[scheme]; !ok {
+		return reference{}, false
+	}
+	return reference{scheme: scheme, body: value[idx+3:]}, true
+}
+
+func replaceReferences(value string, resolvers map[string]Resolver, values map[string]map[string]string) string {
+	if ref, ok := wholeReference(value, resolvers); ok {
+		return values[ref.scheme][ref.body]
+	}
+	return referencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		m := referencePattern.FindStringSubmatch(match)
+		if resolved, ok := values[m[1]][m[2]]; ok {
+			return resolved
+		}
+		return match
+	})
+}
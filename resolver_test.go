@@ -0,0 +1,110 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type resolverTestConfig struct {
+	Password string                 `json:"password"`
+	URL      string                 `json:"url"`
+	Key      string                 `json:"key"`
+	Hosts    []string               `json:"hosts"`
+	Extra    map[string]interface{} `json:"extra"`
+	Inner    *resolverTestInner     `json:"inner"`
+}
+
+type resolverTestInner struct {
+	Token string `json:"token"`
+}
+
+type staticTestResolver struct {
+	scheme string
+	values map[string]string
+	calls  [][]string
+}
+
+func (r *staticTestResolver) Scheme() string {
+	return r.scheme
+}
+
+func (r *staticTestResolver) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	r.calls = append(r.calls, refs)
+	return r.values, nil
+}
+
+func TestResolveReferences(t *testing.T) {
+	// prepare
+	t.Setenv("RESOLVER_TEST_HOST", "db.local")
+	t.Setenv("RESOLVER_TEST_TOKEN", "token")
+	keyPath := filepath.Join(t.TempDir(), "key")
+	assert.Nil(t, os.WriteFile(keyPath, []byte("file-key\n"), 0600))
+	cfg := &resolverTestConfig{
+		URL:   "postgres://${env:RESOLVER_TEST_HOST}:5432/app",
+		Key:   "file://" + keyPath,
+		Hosts: []string{"env://RESOLVER_TEST_HOST", "static"},
+		Extra: map[string]interface{}{"token": "${env:RESOLVER_TEST_TOKEN}", "port": 5432},
+		Inner: &resolverTestInner{Token: "env://RESOLVER_TEST_TOKEN"},
+	}
+
+	// make test
+	err := ResolveReferences(cfg)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres://db.local:5432/app", cfg.URL)
+	assert.Equal(t, "file-key", cfg.Key)
+	assert.Equal(t, []string{"db.local", "static"}, cfg.Hosts)
+	assert.Equal(t, map[string]interface{}{"token": "token", "port": 5432}, cfg.Extra)
+	assert.Equal(t, "token", cfg.Inner.Token)
+}
+
+func TestResolveReferences_CustomResolver(t *testing.T) {
+	// prepare
+	resolver := &staticTestResolver{scheme: "vault", values: map[string]string{"secret/db#password": "pass"}}
+	cfg := &resolverTestConfig{Password: "vault://secret/db#password", URL: "user:${vault:secret/db#password}@db"}
+
+	// make test
+	err := ResolveReferences(cfg, resolver)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "pass", cfg.Password)
+	assert.Equal(t, "user:pass@db", cfg.URL)
+	assert.Equal(t, [][]string{{"secret/db#password"}}, resolver.calls)
+}
+
+func TestResolveReferences_Fails_ReferenceError(t *testing.T) {
+	// prepare
+	cfg := &resolverTestConfig{Inner: &resolverTestInner{Token: "${env:RESOLVER_TEST_NOT_SET}"}}
+
+	// make test
+	err := ResolveReferences(cfg)
+
+	// assertions
+	var refErr *ReferenceError
+	assert.True(t, errors.As(err, &refErr))
+	assert.Equal(t, "env://RESOLVER_TEST_NOT_SET", refErr.Ref)
+	assert.Equal(t, "Inner.Token", refErr.Path)
+	assert.Equal(t, "${env:RESOLVER_TEST_NOT_SET}", cfg.Inner.Token)
+}
+
+func TestNewConfig_WithResolvingReferences(t *testing.T) {
+	// prepare
+	t.Setenv("RESOLVER_TEST_TOKEN", "token")
+	cfg := &resolverTestConfig{}
+	data := []byte(`{"password":"env://RESOLVER_TEST_TOKEN","url":"http://localhost"}`)
+
+	// make test
+	err := NewConfig(cfg, WithParsingBytes(data, JSON), WithResolvingReferences())
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "token", cfg.Password)
+	assert.Equal(t, "http://localhost", cfg.URL)
+}
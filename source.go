@@ -0,0 +1,109 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+const (
+	defaultFileWatchInterval = time.Second
+)
+
+/*
+Source loads configuration into cfg, NewConfig applies sources in passed order, so later sources override earlier ones
+Built-in sources are created with WithParsing... functions, own sources could be created with NewSource and NewTreeSource
+or by implementing the interface
+Name describes the source in logs, e.g. "file config.yaml"
+*/
+type Source interface {
+	Name() string
+	Load(ctx context.Context, cfg interface{}) error
+}
+
+/*
+WatchableSource is implemented by sources which could report changes of their data, e.g. source created with WithParsingFile
+Watch calls changed each time data is changed and blocks until ctx is done, see Reloader.WatchSources
+*/
+type WatchableSource interface {
+	Source
+	Watch(ctx context.Context, changed func()) error
+}
+
+type funcSource struct {
+	name string
+	load func(ctx context.Context, cfg interface{}) error
+}
+
+/*
+NewSource creates Source which loads cfg with load function
+
+Example:
+src := NewSource("consul app/config", loadFromConsul) // func(ctx context.Context, cfg interface{}) error
+err := NewConfig(cfg, WithParsingFile("config.yaml", YAML), src)
+*/
+func NewSource(name string, load func(ctx context.Context, cfg interface{}) error) Source {
+	return &funcSource{name: name, load: load}
+}
+
+// NewTreeSource creates Source from function returning nested key tree, keys are matched with json tags of cfg fields
+func NewTreeSource(name string, load func(ctx context.Context) (map[string]interface{}, error)) Source {
+	return NewSource(name, func(ctx context.Context, cfg interface{}) error {
+		tree, err := load(ctx)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(tree)
+		if err != nil {
+			return err
+		}
+		return ParseBytes(data, JSON, cfg)
+	})
+}
+
+func (s *funcSource) Name() string {
+	return s.name
+}
+
+func (s *funcSource) Load(ctx context.Context, cfg interface{}) error {
+	return s.load(ctx, cfg)
+}
+
+// fileSource parses file and watches its modification time and size
+type fileSource struct {
+	path     string
+	fileType FileType
+	interval time.Duration
+}
+
+func (s *fileSource) Name() string {
+	return "file " + s.path
+}
+
+func (s *fileSource) Load(ctx context.Context, cfg interface{}) error {
+	return fileNotFound(ParseFileCtx(ctx, s.path, s.fileType, cfg), s.path)
+}
+
+// Watch polls file each second, file which does not exist yet is reported as changed as soon as it is created
+func (s *fileSource) Watch(ctx context.Context, changed func()) error {
+	var version fileVersion
+	if info, err := os.Stat(s.path); err == nil {
+		version.remember(info)
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			info, err := os.Stat(s.path)
+			if err != nil || (version.known && info.ModTime().Equal(version.mod) && info.Size() == version.size) {
+				continue
+			}
+			version.remember(info)
+			changed()
+		}
+	}
+}
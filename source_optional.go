@@ -0,0 +1,167 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrSourceNotFound should be returned (or wrapped) by own sources which have nothing to load, see Optional and FirstOf
+var ErrSourceNotFound = errors.New("source not found")
+
+/*
+IsSourceNotFound reports whether err means that source has nothing to load:
+file passed to WithParsingFile does not exist, Vault has nothing under path read by Vault source
+or own source returned ErrSourceNotFound
+Other missing files or paths, e.g. VAULT_CACERT or file:// reference, are errors, so they are not matched
+*/
+func IsSourceNotFound(err error) bool {
+	return errors.Is(err, ErrSourceNotFound)
+}
+
+// sourceNotFoundError marks error caused by missing target of the source, it matches both ErrSourceNotFound and the original error
+type sourceNotFoundError struct {
+	err error
+}
+
+func (e sourceNotFoundError) Error() string {
+	return e.err.Error()
+}
+
+func (e sourceNotFoundError) Unwrap() error {
+	return e.err
+}
+
+func (e sourceNotFoundError) Is(target error) bool {
+	return target == ErrSourceNotFound
+}
+
+// fileNotFound marks err as ErrSourceNotFound if it is caused by missing file at path
+func fileNotFound(err error, path string) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) && pathErr.Path == path && errors.Is(pathErr.Err, fs.ErrNotExist) {
+		return sourceNotFoundError{err: err}
+	}
+	return err
+}
+
+// vaultNotFound marks err as ErrSourceNotFound if Vault has nothing under secret path, errors of client creation or login are kept as is
+func vaultNotFound(err error) error {
+	var pathErr *vaultPathNotFoundError
+	if errors.As(err, &pathErr) {
+		return sourceNotFoundError{err: err}
+	}
+	return err
+}
+
+/*
+Optional wraps src, so it is skipped if it has nothing to load (see IsSourceNotFound), other errors are returned as is
+
+Example:
+err := NewConfig(cfg, WithParsingFile("config.yaml", YAML), Optional(WithParsingFile("config.local.yaml", YAML)))
+*/
+func Optional(src Source) Source {
+	return composeSource("optional "+src.Name(), func(ctx context.Context, cfg interface{}) error {
+		if err := src.Load(ctx, cfg); err != nil && !IsSourceNotFound(err) {
+			return err
+		}
+		return nil
+	}, src)
+}
+
+/*
+When wraps src, so it is loaded only if predicate returns true, predicate is checked on each load and reload
+
+Example:
+err := NewConfig(cfg, WithParsingFile("config.yaml", YAML), When(IfEnv("APP_ENV", "dev"), WithParsingFile("config.dev.yaml", YAML)))
+*/
+func When(predicate func() bool, src Source) Source {
+	return composeSource("when "+src.Name(), func(ctx context.Context, cfg interface{}) error {
+		if !predicate() {
+			return nil
+		}
+		return src.Load(ctx, cfg)
+	}, src)
+}
+
+// IfEnv returns predicate for When which is true if Environment variable key equals value
+func IfEnv(key, value string) func() bool {
+	return func() bool {
+		return os.Getenv(key) == value
+	}
+}
+
+/*
+FirstOf loads the first of sources which has something to load (see IsSourceNotFound), the rest are skipped
+Returning error wrapping ErrSourceNotFound if none of sources has something to load, so FirstOf could be wrapped with Optional
+
+Example:
+err := NewConfig(cfg, FirstOf(WithParsingFile("/etc/app/config.yaml", YAML), WithParsingFile("config.yaml", YAML)))
+*/
+func FirstOf(sources ...Source) Source {
+	names := make([]string, 0, len(sources))
+	for _, src := range sources {
+		names = append(names, src.Name())
+	}
+	name := "first of (" + strings.Join(names, ", ") + ")"
+	return composeSource(name, func(ctx context.Context, cfg interface{}) error {
+		for _, src := range sources {
+			err := src.Load(ctx, cfg)
+			if !IsSourceNotFound(err) {
+				return err
+			}
+		}
+		return fmt.Errorf("%w: none of %s", ErrSourceNotFound, strings.Join(names, ", "))
+	}, sources...)
+}
+
+// composedSource wraps other sources
+type composedSource struct {
+	*funcSource
+	sources []Source
+}
+
+// watchableComposedSource watches wrapped sources which implement WatchableSource
+type watchableComposedSource struct {
+	*composedSource
+}
+
+// composeSource returns WatchableSource if at least one of wrapped sources could be watched
+func composeSource(name string, load func(ctx context.Context, cfg interface{}) error, sources ...Source) Source {
+	s := &composedSource{funcSource: &funcSource{name: name, load: load}, sources: sources}
+	for _, src := range sources {
+		if _, ok := src.(WatchableSource); ok {
+			return watchableComposedSource{composedSource: s}
+		}
+	}
+	return s
+}
+
+// Watch watches all wrapped sources until ctx is done, returning the first error of them
+func (s watchableComposedSource) Watch(ctx context.Context, changed func()) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(s.sources))
+	for _, src := range s.sources {
+		w, ok := src.(WatchableSource)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(w WatchableSource) {
+			defer wg.Done()
+			errs <- w.Watch(ctx, changed)
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil && !errors.Is(err, ctx.Err()) {
+			return err
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vielendanke/go-config/configtest"
+)
+
+func TestOptional(t *testing.T) {
+	// prepare
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	writeReloadFile(t, path, `{"host":"base","inner":{"port":1}}`)
+	cfg := &reloadTestConfig{Inner: &reloadTestInner{}}
+
+	// make test
+	err := NewConfig(cfg, WithParsingFile(path, JSON), Optional(WithParsingFile(filepath.Join(dir, "config.local.json"), JSON)))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "base", cfg.Host)
+}
+
+func TestOptional_Fails_InvalidFile(t *testing.T) {
+	// prepare
+	path := filepath.Join(t.TempDir(), "config.local.json")
+	writeReloadFile(t, path, `{"host":`)
+
+	// make test
+	err := NewConfig(&reloadTestConfig{}, Optional(WithParsingFile(path, JSON)))
+
+	// assertions
+	assert.NotNil(t, err)
+}
+
+func TestOptional_VaultPathNotFound(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteSecret("secret/app", map[string]interface{}{"host": "vault"})
+	cli, cliErr := NewVaultClient(WithVaultAddress(vault.URL()), WithVaultToken(vault.RootToken()))
+	assert.Nil(t, cliErr)
+	cfg := &reloadTestConfig{}
+
+	// make test
+	err := NewConfig(cfg, WithParsingVaultPaths(cli, "secret/app"), Optional(WithParsingVaultPaths(cli, "secret/app-local")))
+	requiredErr := NewConfig(&reloadTestConfig{}, WithParsingVaultPaths(cli, "secret/app-local"))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "vault", cfg.Host)
+	assert.True(t, errors.Is(requiredErr, ErrVaultPathNotFound))
+}
+
+func TestWhen(t *testing.T) {
+	// prepare
+	t.Setenv("APP_ENV", "prod")
+	cfg := &reloadTestConfig{}
+	dev := When(IfEnv("APP_ENV", "dev"), WithParsingBytes([]byte(`{"host":"dev"}`), JSON))
+	prod := When(IfEnv("APP_ENV", "prod"), WithParsingBytes([]byte(`{"host":"prod"}`), JSON))
+
+	// make test
+	err := NewConfig(cfg, prod, dev)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", cfg.Host)
+	assert.Equal(t, "when bytes", dev.Name())
+}
+
+func TestFirstOf(t *testing.T) {
+	// prepare
+	dir := t.TempDir()
+	second := filepath.Join(dir, "second.json")
+	third := filepath.Join(dir, "third.json")
+	writeReloadFile(t, second, `{"host":"second"}`)
+	writeReloadFile(t, third, `{"host":"third"}`)
+	cfg := &reloadTestConfig{}
+
+	// make test
+	err := NewConfig(cfg, FirstOf(WithParsingFile(filepath.Join(dir, "first.json"), JSON), WithParsingFile(second, JSON), WithParsingFile(third, JSON)))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "second", cfg.Host)
+}
+
+func TestFirstOf_NoneFound(t *testing.T) {
+	// prepare
+	dir := t.TempDir()
+	src := FirstOf(WithParsingFile(filepath.Join(dir, "first.json"), JSON), WithParsingFile(filepath.Join(dir, "second.json"), JSON))
+
+	// make test
+	err := NewConfig(&reloadTestConfig{}, src)
+	optionalErr := NewConfig(&reloadTestConfig{}, Optional(src))
+
+	// assertions
+	assert.True(t, errors.Is(err, ErrSourceNotFound))
+	assert.Contains(t, err.Error(), "first.json")
+	assert.Nil(t, optionalErr)
+}
+
+func TestOptional_Watch(t *testing.T) {
+	// prepare
+	path := filepath.Join(t.TempDir(), "config.local.json")
+	file := WithParsingFile(path, JSON)
+	file.(*fileSource).interval = 10 * time.Millisecond
+	src, ok := Optional(file).(WatchableSource)
+	_, envOk := Optional(WithParsingEnv()).(WatchableSource)
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan struct{}, 10)
+	done := make(chan error)
+
+	// make test
+	go func() {
+		done <- src.Watch(ctx, func() { changes <- struct{}{} })
+	}()
+	time.Sleep(30 * time.Millisecond)
+	writeReloadFile(t, path, `{"host":"local"}`)
+	<-changes
+	cancel()
+
+	// assertions
+	assert.True(t, ok)
+	assert.False(t, envOk)
+	assert.True(t, errors.Is(<-done, context.Canceled))
+}
+
+func TestOptional_Fails_VaultCACertNotFound(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	t.Setenv("VAULT_ADDR", vault.URL())
+	t.Setenv("VAULT_TOKEN", vault.RootToken())
+	t.Setenv("VAULT_CACERT", filepath.Join(t.TempDir(), "ca.pem"))
+
+	// make test
+	err := NewConfig(&reloadTestConfig{}, Optional(WithParsingVault("secret/app")))
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.False(t, IsSourceNotFound(err))
+}
+
+func TestOptional_Fails_ReferencedFileNotFound(t *testing.T) {
+	// prepare
+	missing := filepath.Join(t.TempDir(), "password")
+	data := []byte(`{"host":"file://` + missing + `"}`)
+
+	// make test
+	err := NewConfig(&reloadTestConfig{}, WithParsingBytes(data, JSON), Optional(WithResolvingReferences()))
+
+	// assertions
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.False(t, IsSourceNotFound(err))
+}
+
+func TestIsSourceNotFound(t *testing.T) {
+	// prepare
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "config.json")
+	vault := configtest.NewVault(t)
+	cli := newFakeVaultClient(t, vault)
+
+	// make test
+	fileErr := WithParsingFile(missing, JSON).Load(context.Background(), &reloadTestConfig{})
+	vaultErr := WithParsingVaultPaths(cli, "secret/missing").Load(context.Background(), &reloadTestConfig{})
+	readErr := cli.ReadInto("secret/missing", &reloadTestConfig{})
+
+	// assertions
+	assert.True(t, IsSourceNotFound(fileErr))
+	assert.True(t, errors.Is(fileErr, fs.ErrNotExist))
+	assert.True(t, IsSourceNotFound(vaultErr))
+	assert.True(t, errors.Is(vaultErr, ErrVaultPathNotFound))
+	assert.True(t, errors.Is(readErr, ErrVaultPathNotFound))
+	assert.False(t, IsSourceNotFound(readErr))
+	assert.False(t, IsSourceNotFound(fs.ErrNotExist))
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTreeSource(t *testing.T) {
+	// prepare
+	cfg := &reloadTestConfig{Inner: &reloadTestInner{}}
+	src := NewTreeSource("tree", func(ctx context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{"host": "db", "inner": map[string]interface{}{"port": 5432}}, nil
+	})
+
+	// make test
+	err := NewConfig(cfg, WithParsingEnv(), src)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "tree", src.Name())
+	assert.Equal(t, "db", cfg.Host)
+	assert.Equal(t, 5432, cfg.Inner.Port)
+}
+
+func TestNewTreeSource_Fails(t *testing.T) {
+	// prepare
+	loadErr := errors.New("unavailable")
+	src := NewTreeSource("tree", func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, loadErr
+	})
+
+	// make test
+	err := NewConfig(&reloadTestConfig{}, src)
+
+	// assertions
+	assert.True(t, errors.Is(err, loadErr))
+}
+
+func TestBuiltinSources(t *testing.T) {
+	// make test
+	file, fileOk := WithParsingFile("config.yaml", YAML).(WatchableSource)
+	_, envOk := WithParsingEnv().(WatchableSource)
+
+	// assertions
+	assert.True(t, fileOk)
+	assert.False(t, envOk)
+	assert.Equal(t, "file config.yaml", file.Name())
+	assert.Equal(t, "vault secret/app", WithParsingVaultClient(nil, "secret/app").Name())
+}
+
+func TestFileSource_Watch(t *testing.T) {
+	// prepare
+	path := filepath.Join(t.TempDir(), "config.json")
+	src := &fileSource{path: path, fileType: JSON, interval: 10 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan struct{}, 10)
+	done := make(chan error)
+
+	// make test
+	go func() {
+		done <- src.Watch(ctx, func() { changes <- struct{}{} })
+	}()
+	time.Sleep(30 * time.Millisecond)
+	writeReloadFile(t, path, `{"host":"first"}`)
+	<-changes
+	writeReloadFile(t, path, `{"host":"second-host"}`)
+	<-changes
+	cancel()
+
+	// assertions
+	assert.True(t, errors.Is(<-done, context.Canceled))
+	assert.Equal(t, 0, len(changes))
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	envVaultRoleID       = "VAULT_ROLE_ID"
+	envVaultSecretIDFile = "VAULT_SECRET_ID_FILE"
+
+	defaultAppRoleMountPath = "approle"
+)
+
+/*
+AppRoleAuth logs in to Vault using AppRole auth method
+SecretIDFile is read on each login, so secret_id could be rotated without restart
+If WrappedSecretID is true, secret_id is treated as response-wrapping token, it is unwrapped once and cached
+until secret_id changes, creation path of wrapping token is checked against AllowedWrappingPaths before unwrapping,
+by default only secret_id generated for a role of the same AppRole mount is accepted
+
+Example:
+cli, err := NewVaultClient(WithVaultAppRole(&AppRoleAuth{RoleID: "role", SecretIDFile: "/run/secrets/secret_id"}))
+*/
+type AppRoleAuth struct {
+	RoleID          string
+	SecretID        string
+	SecretIDFile    string
+	WrappedSecretID bool
+	// AllowedWrappingPaths are exact paths or path.Match patterns, default is "auth/<mount>/role/*/secret-id"
+	AllowedWrappingPaths []string
+	// MountPath of AppRole auth method, default is "approle"
+	MountPath string
+
+	mu            sync.Mutex
+	wrappingToken string
+	unwrappedID   string
+}
+
+// AppRoleAuthFromEnv creates AppRoleAuth using VAULT_ROLE_ID and VAULT_SECRET_ID_FILE Environment variables
+func AppRoleAuthFromEnv() *AppRoleAuth {
+	return &AppRoleAuth{
+		RoleID:       os.Getenv(envVaultRoleID),
+		SecretIDFile: os.Getenv(envVaultSecretIDFile),
+	}
+}
+
+// WithVaultAppRole configures client to log in with AppRole, token is cached and refreshed when it expires
+func WithVaultAppRole(auth *AppRoleAuth) vaultClientOption {
+	return WithVaultAuth(auth)
+}
+
+// Login logs in via auth/<mount>/login
+func (a *AppRoleAuth) Login(ctx context.Context, cli *api.Client) (*api.Secret, error) {
+	if a.RoleID == "" {
+		return nil, errors.New("AppRole role_id is not set, use RoleID or VAULT_ROLE_ID Environment variable")
+	}
+	secretID, err := a.secretID(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+	return vaultLogin(ctx, cli, authLoginPath(a.MountPath, defaultAppRoleMountPath), map[string]interface{}{
+		"role_id":   a.RoleID,
+		"secret_id": secretID,
+	})
+}
+
+func (a *AppRoleAuth) secretID(ctx context.Context, cli *api.Client) (string, error) {
+	secretID := a.SecretID
+	if a.SecretIDFile != "" {
+		data, err := os.ReadFile(a.SecretIDFile)
+		if err != nil {
+			return "", fmt.Errorf("reading AppRole secret_id: %w", err)
+		}
+		secretID = strings.TrimSpace(string(data))
+	}
+	if secretID == "" {
+		return "", errors.New("AppRole secret_id is not set, use SecretID, SecretIDFile or VAULT_SECRET_ID_FILE Environment variable")
+	}
+	if !a.WrappedSecretID {
+		return secretID, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.wrappingToken == secretID {
+		return a.unwrappedID, nil
+	}
+	secret, err := unwrapAllowed(ctx, clientSender(cli), secretID, a.allowedWrappingPaths())
+	if err != nil {
+		return "", fmt.Errorf("unwrapping AppRole secret_id: %w", err)
+	}
+	unwrapped, ok := secret.Data["secret_id"].(string)
+	if !ok || unwrapped == "" {
+		return "", errors.New("unwrapping AppRole secret_id: response does not contain secret_id")
+	}
+	a.wrappingToken, a.unwrappedID = secretID, unwrapped
+	return unwrapped, nil
+}
+
+// allowedWrappingPaths returns AllowedWrappingPaths or secret_id generation path of the mount if they are not set
+func (a *AppRoleAuth) allowedWrappingPaths() []string {
+	if len(a.AllowedWrappingPaths) > 0 {
+		return a.AllowedWrappingPaths
+	}
+	mount := strings.Trim(a.MountPath, "/")
+	if mount == "" {
+		mount = defaultAppRoleMountPath
+	}
+	return []string{fmt.Sprintf("auth/%s/role/*/secret-id", mount)}
+}
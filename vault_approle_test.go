@@ -0,0 +1,197 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type appRoleTestServer struct {
+	logins     int
+	unwraps    int
+	secretIDs  []string
+	leaseTTL   int
+	denyTokens map[string]bool
+	// creationPath of wrapping tokens, default is "auth/approle/role/app/secret-id"
+	creationPath string
+}
+
+func (s *appRoleTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/v1/auth/approle/login":
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.logins++
+		s.secretIDs = append(s.secretIDs, body["secret_id"])
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": fmt.Sprintf("token-%d", s.logins), "lease_duration": s.leaseTTL},
+		})
+	case "/v1/sys/wrapping/lookup":
+		creationPath := s.creationPath
+		if creationPath == "" {
+			creationPath = "auth/approle/role/app/secret-id"
+		}
+		writeVaultResponse(w, map[string]interface{}{"creation_path": creationPath})
+	case "/v1/sys/wrapping/unwrap":
+		s.unwraps++
+		writeVaultResponse(w, map[string]interface{}{"secret_id": "unwrapped-" + r.Header.Get("X-Vault-Token")})
+	default:
+		token := r.Header.Get("X-Vault-Token")
+		if s.denyTokens[token] {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		writeVaultResponse(w, map[string]interface{}{"token": token})
+	}
+}
+
+func writeSecretIDFile(t *testing.T, secretID string) string {
+	path := filepath.Join(t.TempDir(), "secret_id")
+	if err := os.WriteFile(path, []byte(secretID+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVaultClient_AppRole_CachesToken(t *testing.T) {
+	// prepare
+	fake := &appRoleTestServer{leaseTTL: 3600}
+	srv := newVaultTestServer(t, fake.handler)
+	auth := &AppRoleAuth{RoleID: "role", SecretIDFile: writeSecretIDFile(t, "secret")}
+	cli := newVaultTestClient(t, srv, WithVaultToken(""), WithVaultAppRole(auth))
+
+	// make test
+	first, firstErr := cli.Read("secret/data/test")
+	second, secondErr := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secondErr)
+	assert.Equal(t, 1, fake.logins)
+	assert.Equal(t, []string{"secret"}, fake.secretIDs)
+	assert.Equal(t, "token-1", first.Data["token"])
+	assert.Equal(t, "token-1", second.Data["token"])
+}
+
+func TestVaultClient_AppRole_ReauthenticatesWhenExpired(t *testing.T) {
+	// prepare
+	fake := &appRoleTestServer{leaseTTL: 1}
+	srv := newVaultTestServer(t, fake.handler)
+	auth := &AppRoleAuth{RoleID: "role", SecretID: "secret"}
+	cli := newVaultTestClient(t, srv, WithVaultAppRole(auth))
+
+	// make test
+	_, firstErr := cli.Read("secret/data/test")
+	time.Sleep(time.Second)
+	secret, secondErr := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secondErr)
+	assert.Equal(t, 2, fake.logins)
+	assert.Equal(t, "token-2", secret.Data["token"])
+}
+
+func TestVaultClient_AppRole_ReauthenticatesWhenDenied(t *testing.T) {
+	// prepare
+	fake := &appRoleTestServer{leaseTTL: 3600, denyTokens: map[string]bool{"token-1": true}}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv, WithVaultAppRole(&AppRoleAuth{RoleID: "role", SecretID: "secret"}))
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, 2, fake.logins)
+	assert.Equal(t, "token-2", secret.Data["token"])
+}
+
+func TestVaultClient_AppRole_WrappedSecretID(t *testing.T) {
+	// prepare
+	fake := &appRoleTestServer{leaseTTL: 1}
+	srv := newVaultTestServer(t, fake.handler)
+	auth := &AppRoleAuth{RoleID: "role", SecretIDFile: writeSecretIDFile(t, "wrapping"), WrappedSecretID: true}
+	cli := newVaultTestClient(t, srv, WithVaultAppRole(auth))
+
+	// make test
+	_, firstErr := cli.Read("secret/data/test")
+	time.Sleep(time.Second)
+	_, secondErr := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secondErr)
+	assert.Equal(t, 1, fake.unwraps)
+	assert.Equal(t, []string{"unwrapped-wrapping", "unwrapped-wrapping"}, fake.secretIDs)
+}
+
+func TestVaultClient_AppRole_WrappedSecretID_Fails_CreationPathNotAllowed(t *testing.T) {
+	// prepare
+	fake := &appRoleTestServer{leaseTTL: 3600, creationPath: "sys/wrapping/wrap"}
+	srv := newVaultTestServer(t, fake.handler)
+	auth := &AppRoleAuth{RoleID: "role", SecretID: "wrapping", WrappedSecretID: true}
+	cli := newVaultTestClient(t, srv, WithVaultAppRole(auth))
+
+	// make test
+	_, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.True(t, errors.Is(err, ErrVaultWrappingPath))
+	assert.Equal(t, 0, fake.unwraps)
+	assert.Equal(t, 0, fake.logins)
+}
+
+func TestVaultClient_AppRole_WrappedSecretID_CustomMount(t *testing.T) {
+	// prepare
+	fake := &appRoleTestServer{leaseTTL: 3600, creationPath: "auth/approle/role/app/secret-id"}
+	srv := newVaultTestServer(t, fake.handler)
+	auth := &AppRoleAuth{RoleID: "role", SecretID: "wrapping", WrappedSecretID: true, MountPath: "team-approle"}
+	cli := newVaultTestClient(t, srv, WithVaultAppRole(auth))
+
+	// make test
+	_, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.True(t, errors.Is(err, ErrVaultWrappingPath))
+	assert.Equal(t, 0, fake.unwraps)
+}
+
+func TestAppRoleAuthFromEnv(t *testing.T) {
+	// prepare
+	fake := &appRoleTestServer{leaseTTL: 3600}
+	srv := newVaultTestServer(t, fake.handler)
+	t.Setenv(envVaultRoleID, "role")
+	t.Setenv(envVaultSecretIDFile, writeSecretIDFile(t, "from-env"))
+	cli := newVaultTestClient(t, srv, WithVaultAppRole(AppRoleAuthFromEnv()))
+
+	// make test
+	_, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"from-env"}, fake.secretIDs)
+}
+
+func TestVaultClient_AppRole_Fails_RoleIDIsEmpty(t *testing.T) {
+	// prepare
+	fake := &appRoleTestServer{leaseTTL: 3600}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv, WithVaultAppRole(&AppRoleAuth{SecretID: "secret"}))
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, secret)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, fake.logins)
+}
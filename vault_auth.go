@@ -0,0 +1,196 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+/*
+AuthMethod logs in to Vault, returned secret should contain Auth with client token
+Built-in methods are TokenAuth, AppRoleAuth, KubernetesAuth, JWTAuth, CertAuth, WrappedTokenAuth and TokenChainAuth, own methods could be passed with WithVaultAuth
+Login is called with client which is used for requests, it should not change client settings
+*/
+type AuthMethod interface {
+	Login(ctx context.Context, cli *api.Client) (*api.Secret, error)
+}
+
+// TokenAuth uses static token, it never expires on client side
+type TokenAuth string
+
+// WithVaultAuth configures client to log in with auth method, token is cached and refreshed when it expires
+func WithVaultAuth(auth AuthMethod) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.auth = auth
+	}
+}
+
+// Login returns token itself
+func (t TokenAuth) Login(ctx context.Context, cli *api.Client) (*api.Secret, error) {
+	if t == "" {
+		return nil, errors.New("token is empty")
+	}
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: string(t)}}, nil
+}
+
+// rotatingAuthMethod is implemented by auth methods which credentials are stored in files,
+// client logs in again as soon as credentials were rotated
+type rotatingAuthMethod interface {
+	rotated() bool
+}
+
+// authenticate logs in if auth method is configured and there is no token, the token is expired
+// or auth method credentials were rotated
+func (vc *VaultClient) authenticate(ctx context.Context) error {
+	if vc.auth == nil {
+		return nil
+	}
+	vc.session.mu.Lock()
+	defer vc.session.mu.Unlock()
+
+	if vc.client.Token() != "" && (vc.session.tokenExpiry.IsZero() || time.Now().Before(vc.session.tokenExpiry)) && !authRotated(vc.auth) {
+		return nil
+	}
+	return vc.login(ctx)
+}
+
+func authRotated(auth AuthMethod) bool {
+	r, ok := auth.(rotatingAuthMethod)
+	return ok && r.rotated()
+}
+
+// reauthenticate logs in again if token was not already replaced by concurrent call
+func (vc *VaultClient) reauthenticate(ctx context.Context, staleToken string) error {
+	vc.session.mu.Lock()
+	defer vc.session.mu.Unlock()
+
+	if vc.client.Token() != staleToken {
+		return nil
+	}
+	return vc.login(ctx)
+}
+
+func (vc *VaultClient) login(ctx context.Context) error {
+	secret, err := vc.auth.Login(ctx, vc.client)
+	if err != nil {
+		return fmt.Errorf("vault login failed: %w", err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return errors.New("vault login failed: response does not contain client token")
+	}
+	vc.client.SetToken(secret.Auth.ClientToken)
+	vc.session.tokenExpiry = tokenExpiry(secret.Auth.LeaseDuration)
+	return nil
+}
+
+// setTokenTTL updates expiry of the current token after renewal
+func (vc *VaultClient) setTokenTTL(ttl int) {
+	vc.session.mu.Lock()
+	defer vc.session.mu.Unlock()
+	vc.session.tokenExpiry = tokenExpiry(ttl)
+}
+
+// tokenExpiry returns time when token with ttl in seconds should be considered as expired
+// token is refreshed a bit earlier than Vault revokes it, zero time means token never expires
+func tokenExpiry(ttl int) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	lease := time.Duration(ttl) * time.Second
+	return time.Now().Add(lease - lease/10)
+}
+
+// fileVersion remembers modification time and size of credentials file to detect its rotation
+type fileVersion struct {
+	mu    sync.Mutex
+	mod   time.Time
+	size  int64
+	known bool
+}
+
+func (f *fileVersion) remember(info os.FileInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mod, f.size, f.known = info.ModTime(), info.Size(), true
+}
+
+func (f *fileVersion) changed(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.known && (!info.ModTime().Equal(f.mod) || info.Size() != f.size)
+}
+
+// readCredentialsFile reads trimmed file content and remembers its version
+func readCredentialsFile(path string, version *fileVersion) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	version.remember(info)
+	return strings.TrimSpace(string(data)), nil
+}
+
+// authLoginPath returns login path of auth method mounted to mount or to defaultMount if mount is empty
+func authLoginPath(mount, defaultMount string) string {
+	if mount == "" {
+		mount = defaultMount
+	}
+	return fmt.Sprintf("auth/%s/login", strings.Trim(mount, "/"))
+}
+
+// vaultSender writes body to path with token instead of the client token, empty token means request is sent without token
+type vaultSender func(ctx context.Context, path, token string, body interface{}) (*api.Secret, error)
+
+// clientSender sends requests with cli, so they go to its address and namespace, it is used by auth methods
+// Logins are made in namespace the client was created with, since token is shared by clients returned from WithNamespace
+func clientSender(cli *api.Client) vaultSender {
+	return func(ctx context.Context, path, token string, body interface{}) (*api.Secret, error) {
+		r := cli.NewRequest(http.MethodPut, "/v1/"+path)
+		r.ClientToken = token
+		if body != nil {
+			if err := r.SetJSONBody(body); err != nil {
+				return nil, err
+			}
+		}
+		return doVaultRequest(ctx, cli, r)
+	}
+}
+
+// vaultLogin writes data to login path without sending current client token
+func vaultLogin(ctx context.Context, cli *api.Client, path string, data map[string]interface{}) (*api.Secret, error) {
+	secret, err := clientSender(cli)(ctx, path, "", data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("%w: %s", ErrVaultPathNotFound, path)
+	}
+	return secret, nil
+}
+
+// vaultUnwrap unwraps response-wrapping token and returns wrapped secret
+func vaultUnwrap(ctx context.Context, send vaultSender, wrappingToken string) (*api.Secret, error) {
+	secret, err := send(ctx, "sys/wrapping/unwrap", wrappingToken, nil)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, errors.New("wrapping token could not be unwrapped")
+	}
+	return secret, nil
+}
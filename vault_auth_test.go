@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+type loginTestServer struct {
+	logins []map[string]interface{}
+}
+
+func (s *loginTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		body["path"] = r.URL.Path
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			body["cn"] = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		s.logins = append(s.logins, body)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": fmt.Sprintf("token-%d", len(s.logins)), "lease_duration": 3600},
+		})
+		return
+	}
+	writeVaultResponse(w, map[string]interface{}{"token": r.Header.Get("X-Vault-Token")})
+}
+
+type customTestAuth struct {
+	calls int
+}
+
+func (c *customTestAuth) Login(ctx context.Context, cli *api.Client) (*api.Secret, error) {
+	c.calls++
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: "custom", LeaseDuration: 3600}}, nil
+}
+
+func TestVaultClient_TokenAuth(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&loginTestServer{}).handler)
+	cli := newVaultTestClient(t, srv, WithVaultAuth(TokenAuth("static")))
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "static", secret.Data["token"])
+}
+
+func TestVaultClient_TokenAuth_Fails_TokenIsEmpty(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&loginTestServer{}).handler)
+	cli := newVaultTestClient(t, srv, WithVaultAuth(TokenAuth("")))
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, secret)
+	assert.NotNil(t, err)
+}
+
+func TestVaultClient_CustomAuth(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&loginTestServer{}).handler)
+	auth := &customTestAuth{}
+	cli := newVaultTestClient(t, srv, WithVaultAuth(auth))
+
+	// make test
+	_, _ = cli.Read("secret/data/test")
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, 1, auth.calls)
+	assert.Equal(t, "custom", secret.Data["token"])
+}
+
+func TestVaultClient_JWTAuth(t *testing.T) {
+	// prepare
+	fake := &loginTestServer{}
+	srv := newVaultTestServer(t, fake.handler)
+	jwtPath := filepath.Join(t.TempDir(), "ci.jwt")
+	_ = os.WriteFile(jwtPath, []byte("first"), 0600)
+	cli := newVaultTestClient(t, srv, WithVaultAuth(&JWTAuth{Role: "ci", JWTPath: jwtPath}))
+
+	// make test
+	_, firstErr := cli.Read("secret/data/test")
+	_, cachedErr := cli.Read("secret/data/test")
+	_ = os.WriteFile(jwtPath, []byte("second"), 0600)
+	_ = os.Chtimes(jwtPath, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	secret, rotatedErr := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, cachedErr)
+	assert.Nil(t, rotatedErr)
+	assert.Len(t, fake.logins, 2)
+	assert.Equal(t, map[string]interface{}{"path": "/v1/auth/jwt/login", "role": "ci", "jwt": "first"}, fake.logins[0])
+	assert.Equal(t, "second", fake.logins[1]["jwt"])
+	assert.Equal(t, "token-2", secret.Data["token"])
+}
+
+func TestVaultClient_CertAuth(t *testing.T) {
+	// prepare
+	fake := &loginTestServer{}
+	certPath, keyPath, clientCert := writeTestKeyPair(t, t.TempDir())
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(fake.handler))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	cli := newVaultTestClient(t, srv,
+		WithVaultCACert(writeServerCA(t, srv)),
+		WithVaultClientCert(certPath, keyPath),
+		WithVaultAuth(&CertAuth{Name: "edge", MountPath: "/edge-cert/"}),
+	)
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "token-1", secret.Data["token"])
+	assert.Equal(t, map[string]interface{}{"path": "/v1/auth/edge-cert/login", "name": "edge", "cn": "go-config"}, fake.logins[0])
+}
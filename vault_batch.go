@@ -0,0 +1,102 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+const (
+	defaultBatchParallelism = 4
+)
+
+// VaultBatchResult is a result of reading a single path in batch
+type VaultBatchResult struct {
+	Data map[string]interface{}
+	Err  error
+}
+
+// VaultBatch contains results of ReadBatch, Paths are unique paths in requested order
+type VaultBatch struct {
+	Paths   []string
+	Results map[string]VaultBatchResult
+}
+
+/*
+ReadBatch reads KV secret data stored under paths concurrently, at most parallelism requests are sent at once
+(4 if parallelism is not positive), identical paths are read once
+Path syntax is the same as for ReadData, each path has its own result, failure of one path does not stop others
+
+Example:
+batch := cli.ReadBatch([]string{"secret/app", "secret/db"}, 0)
+err := batch.Merge(cfg)
+*/
+func (vc *VaultClient) ReadBatch(paths []string, parallelism int) *VaultBatch {
+	return vc.ReadBatchCtx(context.Background(), paths, parallelism)
+}
+
+// ReadBatchCtx reads paths like ReadBatch, ctx cancels requests which are not finished yet
+func (vc *VaultClient) ReadBatchCtx(ctx context.Context, paths []string, parallelism int) *VaultBatch {
+	if parallelism <= 0 {
+		parallelism = defaultBatchParallelism
+	}
+	batch := &VaultBatch{Results: make(map[string]VaultBatchResult, len(paths))}
+	for _, path := range paths {
+		if _, ok := batch.Results[path]; ok {
+			continue
+		}
+		batch.Results[path] = VaultBatchResult{}
+		batch.Paths = append(batch.Paths, path)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallelism)
+	for _, path := range batch.Paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(path string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			data, err := vc.readData(ctx, path)
+			mu.Lock()
+			batch.Results[path] = VaultBatchResult{Data: data, Err: err}
+			mu.Unlock()
+		}(path)
+	}
+	wg.Wait()
+	return batch
+}
+
+// Err returns error of the first failed path in requested order, nil if all paths were read
+func (b *VaultBatch) Err() error {
+	for _, path := range b.Paths {
+		if err := b.Results[path].Err; err != nil {
+			return fmt.Errorf("path %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Merge unmarshalls data of all read paths into cfg in requested order, so later paths override keys of earlier ones
+// If any path failed, Returning Err of the batch and cfg is left untouched
+// cfg should be passed as pointer
+func (b *VaultBatch) Merge(cfg interface{}) error {
+	if err := b.Err(); err != nil {
+		return err
+	}
+	for _, path := range b.Paths {
+		result := b.Results[path]
+		data, err := json.Marshal(result.Data)
+		if err != nil {
+			return fmt.Errorf("path %s: %w", path, err)
+		}
+		if err := ParseBytes(data, JSON, cfg); err != nil {
+			return fmt.Errorf("path %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type vaultBatchTestConfig struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+func TestVaultClient_ReadBatch(t *testing.T) {
+	// prepare
+	fake := &vaultParseTestServer{reads: map[string]int{}}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	batch := cli.ReadBatch([]string{"secret/app", "secret/db", "secret/app", "secret/missing"}, 2)
+
+	// assertions
+	assert.Equal(t, []string{"secret/app", "secret/db", "secret/missing"}, batch.Paths)
+	assert.Nil(t, batch.Results["secret/app"].Err)
+	assert.Equal(t, "db.local", batch.Results["secret/app"].Data["host"])
+	assert.Equal(t, "pass", batch.Results["secret/db"].Data["password"])
+	assert.True(t, errors.Is(batch.Results["secret/missing"].Err, ErrVaultPathNotFound))
+	assert.True(t, errors.Is(batch.Err(), ErrVaultPathNotFound))
+	assert.Contains(t, batch.Err().Error(), "secret/missing")
+	assert.Equal(t, 1, fake.reads["secret/data/app"])
+}
+
+func TestVaultClient_ReadBatch_BoundsParallelism(t *testing.T) {
+	// prepare
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/") {
+			writeVaultResponse(w, map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "1"}})
+			return
+		}
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		writeVaultResponse(w, map[string]interface{}{"key": r.URL.Path})
+	})
+	cli := newVaultTestClient(t, srv)
+	paths := []string{"secret/a", "secret/b", "secret/c", "secret/d", "secret/e", "secret/f"}
+
+	// make test
+	batch := cli.ReadBatch(paths, 2)
+
+	// assertions
+	assert.Nil(t, batch.Err())
+	assert.Equal(t, "/v1/secret/f", batch.Results["secret/f"].Data["key"])
+	assert.True(t, maxInFlight <= 2)
+	assert.True(t, maxInFlight > 0)
+}
+
+func TestVaultBatch_Merge(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&vaultParseTestServer{reads: map[string]int{}}).handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &vaultBatchTestConfig{User: "default"}
+
+	// make test
+	err := NewConfig(cfg, WithParsingVaultPaths(cli, "secret/app", "secret/db"))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, &vaultBatchTestConfig{Host: "db.local", Port: "5432", User: "admin", Password: "pass"}, cfg)
+}
+
+func TestVaultBatch_Merge_Fails_PathNotFound(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&vaultParseTestServer{reads: map[string]int{}}).handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &vaultBatchTestConfig{}
+
+	// make test
+	err := cli.ReadBatch([]string{"secret/missing", "secret/db"}, 0).Merge(cfg)
+
+	// assertions
+	assert.True(t, errors.Is(err, ErrVaultPathNotFound))
+	assert.Equal(t, &vaultBatchTestConfig{}, cfg)
+}
@@ -0,0 +1,378 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	envVaultCacheFile         = "VAULT_CACHE_FILE"
+	envVaultCacheMaxStaleness = "VAULT_CACHE_MAX_STALENESS"
+	envVaultCacheKey          = "VAULT_CACHE_KEY"
+	envVaultCacheKeyFile      = "VAULT_CACHE_KEY_FILE"
+
+	// vaultCacheAAD binds encrypted content to its purpose
+	vaultCacheAAD = "go-config vault fallback cache"
+	// vaultCacheRefreshInterval is how often fetch time of unchanged secrets is saved to file
+	vaultCacheRefreshInterval = time.Minute
+)
+
+// VaultCacheFallback describes read which was served from fallback cache because Vault was unreachable
+type VaultCacheFallback struct {
+	Path string
+	// FetchedAt is the time when cached secret was read from Vault
+	FetchedAt time.Time
+	// Err is the error returned by Vault
+	Err error
+}
+
+type vaultCacheSettings struct {
+	path         string
+	maxStaleness time.Duration
+	key          []byte
+	keyFile      string
+	callback     func(VaultCacheFallback)
+}
+
+/*
+WithVaultFallbackCache enables last known good cache of read secrets stored in file encrypted with AES-GCM
+Cache is used only if Vault is unreachable (network errors or 5xx responses), secrets fetched more than maxStaleness ago
+are not used, 0 means no limit, secrets with lease (e.g. dynamic credentials) are never cached
+File is rewritten only when cached secret is changed, fetch time of unchanged secrets is saved at most once a minute
+Key is taken from WithVaultFallbackCacheKey, WithVaultFallbackCacheKeyFile, VAULT_CACHE_KEY (base64) or VAULT_CACHE_KEY_FILE,
+it should be 16, 24 or 32 bytes long
+Every read served from cache is logged, added to the secret warnings and passed to WithVaultFallbackCacheCallback
+Cache could be enabled with VAULT_CACHE_FILE and VAULT_CACHE_MAX_STALENESS (e.g. "1h") as well, so FetchVaultSecret uses it too
+
+Example:
+cli, err := NewVaultClient(WithVaultFallbackCache("/var/cache/app/vault.cache", time.Hour))
+*/
+func WithVaultFallbackCache(path string, maxStaleness time.Duration) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.cache.path = path
+		s.cache.maxStaleness = maxStaleness
+	}
+}
+
+// WithVaultFallbackCacheKey sets key used to encrypt fallback cache
+func WithVaultFallbackCacheKey(key []byte) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.cache.key = key
+	}
+}
+
+// WithVaultFallbackCacheKeyFile sets file with key used to encrypt fallback cache, key could be stored raw or base64 encoded
+func WithVaultFallbackCacheKeyFile(path string) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.cache.keyFile = path
+	}
+}
+
+// WithVaultFallbackCacheCallback sets function called synchronously every time read is served from fallback cache
+func WithVaultFallbackCacheCallback(callback func(VaultCacheFallback)) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.cache.callback = callback
+	}
+}
+
+// vaultCache keeps responses by request key in memory and in encrypted file
+type vaultCache struct {
+	path         string
+	maxStaleness time.Duration
+	aead         cipher.AEAD
+	callback     func(VaultCacheFallback)
+	logger       *log.Logger
+
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]vaultCacheEntry
+	// version is incremented on each change of entries, savedAt is the time of the last change written to file
+	version int
+	savedAt time.Time
+
+	// saveMu serializes writes of file, savedVersion prevents overwriting it with older entries
+	saveMu       sync.Mutex
+	savedVersion int
+}
+
+type vaultCacheEntry struct {
+	FetchedAt time.Time       `json:"fetched_at"`
+	Secret    json.RawMessage `json:"secret"`
+}
+
+func vaultCacheFromEnv() (vaultCacheSettings, error) {
+	s := vaultCacheSettings{path: os.Getenv(envVaultCacheFile)}
+	if staleness := os.Getenv(envVaultCacheMaxStaleness); staleness != "" {
+		d, err := time.ParseDuration(staleness)
+		if err != nil {
+			return s, fmt.Errorf("invalid %s value: %w", envVaultCacheMaxStaleness, err)
+		}
+		s.maxStaleness = d
+	}
+	return s, nil
+}
+
+func newVaultCache(s vaultCacheSettings) (*vaultCache, error) {
+	key, err := vaultCacheKey(s)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("fallback cache key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &vaultCache{
+		path:         s.path,
+		maxStaleness: s.maxStaleness,
+		aead:         aead,
+		callback:     s.callback,
+		logger:       log.New(os.Stderr, "go-config: ", log.LstdFlags),
+	}, nil
+}
+
+func vaultCacheKey(s vaultCacheSettings) ([]byte, error) {
+	switch {
+	case len(s.key) > 0:
+		return s.key, nil
+	case s.keyFile != "":
+		return readVaultCacheKeyFile(s.keyFile)
+	case os.Getenv(envVaultCacheKey) != "":
+		key, err := base64.StdEncoding.DecodeString(os.Getenv(envVaultCacheKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", envVaultCacheKey, err)
+		}
+		return key, nil
+	case os.Getenv(envVaultCacheKeyFile) != "":
+		return readVaultCacheKeyFile(os.Getenv(envVaultCacheKeyFile))
+	default:
+		return nil, fmt.Errorf("fallback cache key is required, set %s or %s", envVaultCacheKey, envVaultCacheKeyFile)
+	}
+}
+
+func readVaultCacheKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fallback cache key: %w", err)
+	}
+	if key, decodeErr := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); decodeErr == nil {
+		switch len(key) {
+		case 16, 24, 32:
+			return key, nil
+		}
+	}
+	return data, nil
+}
+
+// cachedRead reads path, successful responses are stored in fallback cache
+// if Vault is unreachable cached response is returned, report makes it logged and passed to callback
+func (vc *VaultClient) cachedRead(ctx context.Context, path string, params url.Values, report bool) (*api.Secret, error) {
+	secret, err := vc.request(ctx, http.MethodGet, path, nil, params)
+	if vc.cache == nil {
+		return secret, err
+	}
+	key := path
+	if vc.namespace != "" {
+		key = vc.namespace + "/" + path
+	}
+	if len(params) > 0 {
+		key += "?" + params.Encode()
+	}
+	if err == nil {
+		vc.cache.store(key, secret)
+		return secret, nil
+	}
+	if !isVaultUnreachable(err) {
+		return nil, err
+	}
+	cached, fetchedAt, ok := vc.cache.lookup(key)
+	if !ok {
+		return nil, err
+	}
+	cached.Warnings = append(cached.Warnings, fmt.Sprintf("served from fallback cache, fetched at %s", fetchedAt.Format(time.RFC3339)))
+	if report {
+		vc.cache.report(VaultCacheFallback{Path: path, FetchedAt: fetchedAt, Err: err})
+	}
+	return cached, nil
+}
+
+// store saves secret, nil secret removes entry so deleted secrets are not served from cache
+// Secrets with lease are not stored, they are issued for a single client and expire anyway
+func (c *vaultCache) store(key string, secret *api.Secret) {
+	if secret != nil && secret.LeaseID != "" {
+		return
+	}
+	plain, version, ok := c.update(key, secret)
+	if !ok {
+		return
+	}
+	if err := c.save(plain, version); err != nil {
+		c.logger.Printf("fallback cache is not saved: %v", err)
+	}
+}
+
+// update changes entry and returns entries which should be saved, ok is false if file is up to date
+func (c *vaultCache) update(key string, secret *api.Secret) ([]byte, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	now := time.Now()
+	entry, exists := c.entries[key]
+	if secret == nil {
+		if !exists {
+			return nil, 0, false
+		}
+		delete(c.entries, key)
+	} else {
+		// request id differs in every response, so it is not stored to detect unchanged secrets
+		stored := *secret
+		stored.RequestID = ""
+		raw, err := json.Marshal(&stored)
+		if err != nil {
+			return nil, 0, false
+		}
+		c.entries[key] = vaultCacheEntry{FetchedAt: now, Secret: raw}
+		if exists && bytes.Equal(entry.Secret, raw) && now.Sub(c.savedAt) < c.refreshInterval() {
+			return nil, 0, false
+		}
+	}
+	plain, err := json.Marshal(c.entries)
+	if err != nil {
+		return nil, 0, false
+	}
+	c.version++
+	c.savedAt = now
+	return plain, c.version, true
+}
+
+// refreshInterval keeps saved fetch time accurate enough for maxStaleness check after restart
+func (c *vaultCache) refreshInterval() time.Duration {
+	if c.maxStaleness > 0 && c.maxStaleness/2 < vaultCacheRefreshInterval {
+		return c.maxStaleness / 2
+	}
+	return vaultCacheRefreshInterval
+}
+
+func (c *vaultCache) lookup(key string) (*api.Secret, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	entry, ok := c.entries[key]
+	if !ok || (c.maxStaleness > 0 && time.Since(entry.FetchedAt) > c.maxStaleness) {
+		return nil, time.Time{}, false
+	}
+	secret, err := api.ParseSecret(bytes.NewReader(entry.Secret))
+	if err != nil || secret == nil || secret.LeaseID != "" {
+		return nil, time.Time{}, false
+	}
+	return secret, entry.FetchedAt, true
+}
+
+func (c *vaultCache) report(fallback VaultCacheFallback) {
+	c.logger.Printf("vault is unreachable, %s is served from fallback cache fetched at %s: %v",
+		fallback.Path, fallback.FetchedAt.Format(time.RFC3339), fallback.Err)
+	if c.callback != nil {
+		c.callback(fallback)
+	}
+}
+
+// load reads cache file once, missing or unreadable file is treated as empty cache
+func (c *vaultCache) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.entries = map[string]vaultCacheEntry{}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			c.logger.Printf("fallback cache is not loaded: %v", err)
+		}
+		return
+	}
+	plain, err := c.decrypt(data)
+	if err == nil {
+		err = json.Unmarshal(plain, &c.entries)
+	}
+	if err != nil {
+		c.entries = map[string]vaultCacheEntry{}
+		c.logger.Printf("fallback cache is not loaded: %v", err)
+	}
+}
+
+// save encrypts entries of version and writes them to temporary file which is renamed, so cache file is never partially written
+func (c *vaultCache) save(plain []byte, version int) error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	if version <= c.savedVersion {
+		return nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	data := c.aead.Seal(nonce, nonce, plain, []byte(vaultCacheAAD))
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	c.savedVersion = version
+	return nil
+}
+
+func (c *vaultCache) decrypt(data []byte) ([]byte, error) {
+	size := c.aead.NonceSize()
+	if len(data) < size {
+		return nil, errors.New("cache file is too short")
+	}
+	return c.aead.Open(nil, data[:size], data[size:], []byte(vaultCacheAAD))
+}
+
+// isVaultUnreachable reports whether err is caused by network failure or Vault server error
+func isVaultUnreachable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode >= 500
+	}
+	// Vault client returns the last transport error as is when retries are exhausted
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/vielendanke/go-config/configtest"
+)
+
+var vaultCacheTestKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestVaultClient_FallbackCache_VaultUnreachable(t *testing.T) {
+	// prepare
+	cachePath := filepath.Join(t.TempDir(), "vault.cache")
+	srv := newVaultTestServer(t, (&vaultParseTestServer{reads: map[string]int{}}).handler)
+	var fallbacks []VaultCacheFallback
+	cli := newVaultTestClient(t, srv,
+		WithVaultFallbackCache(cachePath, time.Hour),
+		WithVaultFallbackCacheKey(vaultCacheTestKey),
+		WithVaultFallbackCacheCallback(func(f VaultCacheFallback) {
+			fallbacks = append(fallbacks, f)
+		}),
+	)
+	_, err := cli.ReadData("secret/db")
+	assert.Nil(t, err)
+	srv.Close()
+
+	// make test
+	data, err := cli.ReadData("secret/db")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "pass", data["password"])
+	assert.Len(t, fallbacks, 1)
+	assert.Equal(t, "secret/data/db", fallbacks[0].Path)
+	assert.NotNil(t, fallbacks[0].Err)
+	content, readErr := os.ReadFile(cachePath)
+	assert.Nil(t, readErr)
+	assert.False(t, bytes.Contains(content, []byte("pass")))
+}
+
+func TestVaultClient_FallbackCache_NewClientReadsFile(t *testing.T) {
+	// prepare
+	cachePath := filepath.Join(t.TempDir(), "vault.cache")
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeVaultResponse(w, map[string]interface{}{"password": "pass"})
+	})
+	cli := newVaultTestClient(t, srv, WithVaultFallbackCache(cachePath, 0), WithVaultFallbackCacheKey(vaultCacheTestKey))
+	_, err := cli.Read("secret/db")
+	assert.Nil(t, err)
+	srv.Close()
+	restarted := newVaultTestClient(t, srv, WithVaultFallbackCache(cachePath, 0), WithVaultFallbackCacheKey(vaultCacheTestKey))
+
+	// make test
+	secret, err := restarted.Read("secret/db")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "pass", secret.Data["password"])
+	assert.Len(t, secret.Warnings, 1)
+	assert.Contains(t, secret.Warnings[0], "fallback cache")
+}
+
+func TestVaultClient_FallbackCache_Fails_Stale(t *testing.T) {
+	// prepare
+	cachePath := filepath.Join(t.TempDir(), "vault.cache")
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeVaultResponse(w, map[string]interface{}{"password": "pass"})
+	})
+	cli := newVaultTestClient(t, srv, WithVaultFallbackCache(cachePath, time.Millisecond), WithVaultFallbackCacheKey(vaultCacheTestKey))
+	_, err := cli.Read("secret/db")
+	assert.Nil(t, err)
+	srv.Close()
+	time.Sleep(5 * time.Millisecond)
+
+	// make test
+	_, err = cli.Read("secret/db")
+
+	// assertions
+	assert.NotNil(t, err)
+}
+
+func TestVaultClient_FallbackCache_NotUsed_VaultResponds(t *testing.T) {
+	// prepare
+	cachePath := filepath.Join(t.TempDir(), "vault.cache")
+	denied := false
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if denied {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		writeVaultResponse(w, map[string]interface{}{"password": "pass"})
+	})
+	cli := newVaultTestClient(t, srv, WithVaultFallbackCache(cachePath, 0), WithVaultFallbackCacheKey(vaultCacheTestKey))
+	_, err := cli.Read("secret/db")
+	assert.Nil(t, err)
+	denied = true
+
+	// make test
+	_, err = cli.Read("secret/db")
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrVaultPathNotFound))
+}
+
+func TestVaultClient_FallbackCache_Fails_WrongKey(t *testing.T) {
+	// prepare
+	cachePath := filepath.Join(t.TempDir(), "vault.cache")
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeVaultResponse(w, map[string]interface{}{"password": "pass"})
+	})
+	cli := newVaultTestClient(t, srv, WithVaultFallbackCache(cachePath, 0), WithVaultFallbackCacheKey(vaultCacheTestKey))
+	_, err := cli.Read("secret/db")
+	assert.Nil(t, err)
+	srv.Close()
+	other := newVaultTestClient(t, srv, WithVaultFallbackCache(cachePath, 0), WithVaultFallbackCacheKey([]byte("fedcba9876543210fedcba9876543210")))
+
+	// make test
+	_, err = other.Read("secret/db")
+
+	// assertions
+	assert.NotNil(t, err)
+}
+
+func TestNewVaultClient_FallbackCache_KeyFromEnv(t *testing.T) {
+	// prepare
+	keyPath := filepath.Join(t.TempDir(), "cache.key")
+	assert.Nil(t, os.WriteFile(keyPath, []byte("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=\n"), 0600))
+	t.Setenv(envVaultCacheFile, filepath.Join(t.TempDir(), "vault.cache"))
+	t.Setenv(envVaultCacheKeyFile, keyPath)
+
+	// make test
+	cli, err := NewVaultClient(WithVaultAddress("http://127.0.0.1:1"))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.NotNil(t, cli.cache)
+}
+
+func TestNewVaultClient_FallbackCache_Fails_NoKey(t *testing.T) {
+	// make test
+	_, err := NewVaultClient(WithVaultAddress("http://127.0.0.1:1"), WithVaultFallbackCache(filepath.Join(t.TempDir(), "vault.cache"), 0))
+
+	// assertions
+	assert.NotNil(t, err)
+}
+
+func TestVaultClient_FallbackCache_SavesOnlyChanges(t *testing.T) {
+	// prepare
+	cachePath := filepath.Join(t.TempDir(), "vault.cache")
+	vault := configtest.NewVault(t)
+	vault.WriteSecret("secret/db", map[string]interface{}{"password": "first"})
+	cli := newFakeVaultClient(t, vault, WithVaultFallbackCache(cachePath, time.Hour), WithVaultFallbackCacheKey(vaultCacheTestKey))
+	_, firstErr := cli.ReadData("secret/db")
+	first, _ := os.ReadFile(cachePath)
+
+	// make test
+	_, unchangedErr := cli.ReadData("secret/db")
+	unchanged, _ := os.ReadFile(cachePath)
+	vault.WriteSecret("secret/db", map[string]interface{}{"password": "second"})
+	_, changedErr := cli.ReadData("secret/db")
+	changed, _ := os.ReadFile(cachePath)
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, unchangedErr)
+	assert.Nil(t, changedErr)
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, unchanged)
+	assert.NotEqual(t, first, changed)
+}
+
+func TestVaultClient_FallbackCache_SkipsLeasedSecrets(t *testing.T) {
+	// prepare
+	cachePath := filepath.Join(t.TempDir(), "vault.cache")
+	vault := configtest.NewVault(t)
+	vault.WriteLeasedSecret("database/creds/app", map[string]interface{}{"username": "app"}, time.Hour, true)
+	cli := newFakeVaultClient(t, vault, WithVaultFallbackCache(cachePath, time.Hour), WithVaultFallbackCacheKey(vaultCacheTestKey))
+	secret, readErr := cli.Read("database/creds/app")
+	vault.Close()
+
+	// make test
+	_, err := cli.Read("database/creds/app")
+
+	// assertions
+	assert.Nil(t, readErr)
+	assert.NotEmpty(t, secret.LeaseID)
+	assert.NotNil(t, err)
+	_, statErr := os.Stat(cachePath)
+	assert.True(t, errors.Is(statErr, os.ErrNotExist))
+}
+
+func TestIsVaultUnreachable(t *testing.T) {
+	// prepare
+	cases := []struct {
+		err         error
+		unreachable bool
+	}{
+		{err: &url.Error{Op: "Get", URL: "http://vault:8200", Err: errors.New("connection refused")}, unreachable: true},
+		{err: fmt.Errorf("read: %w", &net.OpError{Op: "dial", Err: errors.New("no route to host")}), unreachable: true},
+		{err: &api.ResponseError{StatusCode: http.StatusBadGateway}, unreachable: true},
+		{err: &api.ResponseError{StatusCode: http.StatusForbidden}, unreachable: false},
+		{err: errors.New("GET http://vault:8200 giving up after 3 attempts"), unreachable: false},
+		{err: context.Canceled, unreachable: false},
+		{err: nil, unreachable: false},
+	}
+
+	for _, c := range cases {
+		// make test
+		unreachable := isVaultUnreachable(c.err)
+
+		// assertions
+		assert.Equal(t, c.unreachable, unreachable, "%v", c.err)
+	}
+}
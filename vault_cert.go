@@ -0,0 +1,32 @@
+package config
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const defaultCertMountPath = "cert"
+
+/*
+CertAuth logs in to Vault using TLS certificate auth method
+Client certificate is the one configured with WithVaultClientCert or VAULT_CLIENT_CERT and VAULT_CLIENT_KEY Environment variables
+
+Example:
+cli, err := NewVaultClient(WithVaultClientCert("host.pem", "host-key.pem"), WithVaultAuth(&CertAuth{Name: "edge"}))
+*/
+type CertAuth struct {
+	// Name of the certificate role, if empty Vault tries all roles matching the certificate
+	Name string
+	// MountPath of cert auth method, default is "cert"
+	MountPath string
+}
+
+// Login logs in via auth/<mount>/login
+func (c *CertAuth) Login(ctx context.Context, cli *api.Client) (*api.Secret, error) {
+	data := map[string]interface{}{}
+	if c.Name != "" {
+		data["name"] = c.Name
+	}
+	return vaultLogin(ctx, cli, authLoginPath(c.MountPath, defaultCertMountPath), data)
+}
@@ -0,0 +1,499 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	envVaultAddress = "VAULT_ADDR"
+)
+
+// ErrVaultPathNotFound is returned when Vault has nothing stored under requested path
+var ErrVaultPathNotFound = errors.New("path not found")
+
+// vaultPathNotFoundError is returned by secret reads, so sources could tell their own missing path from other errors,
+// e.g. missing auth mount, it wraps ErrVaultPathNotFound
+type vaultPathNotFoundError struct {
+	path string
+}
+
+func vaultPathNotFound(path string) error {
+	return &vaultPathNotFoundError{path: path}
+}
+
+func (e *vaultPathNotFoundError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrVaultPathNotFound, e.path)
+}
+
+func (e *vaultPathNotFoundError) Unwrap() error {
+	return ErrVaultPathNotFound
+}
+
+// VaultClient is a reusable Vault client, it should be created once with NewVaultClient and shared
+// between calls so the underlying connection pool is reused
+type VaultClient struct {
+	client *api.Client
+	auth   AuthMethod
+	mounts *kvMounts
+	guard  *versionGuard
+	cache  *vaultCache
+
+	// session is shared by clients created with WithNamespace
+	session   *vaultSession
+	namespace string
+	failover  *vaultFailover
+}
+
+// vaultSession holds expiry of the client token
+type vaultSession struct {
+	mu          sync.Mutex
+	tokenExpiry time.Time
+}
+
+type vaultSettings struct {
+	address       string
+	token         string
+	tokenSet      bool
+	httpClient    *http.Client
+	timeout       time.Duration
+	maxRetries    int
+	minRetryWait  time.Duration
+	maxRetryWait  time.Duration
+	checkRetry    retryablehttp.CheckRetry
+	backoff       retryablehttp.Backoff
+	rateLimit     float64
+	rateBurst     int
+	tls           vaultTLSSettings
+	auth          AuthMethod
+	rollbackGuard bool
+	cache         vaultCacheSettings
+	namespace     string
+	addresses     []string
+	recovery      time.Duration
+}
+
+type vaultClientOption func(s *vaultSettings)
+
+// WithVaultAddress sets Vault address, by default it is fetching from VAULT_ADDR Environment variable
+func WithVaultAddress(address string) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.address = address
+	}
+}
+
+// WithVaultToken sets token used for requests, by default it is fetching from VAULT_TOKEN Environment variable
+// Use WithVaultTokenChain to fall back to Vault Agent sink or ~/.vault-token
+func WithVaultToken(token string) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.token = token
+		s.tokenSet = true
+	}
+}
+
+// WithVaultHTTPClient replaces default http.Client, use it for custom transport or proxy settings
+// TLS options are not applied to the passed client
+func WithVaultHTTPClient(client *http.Client) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.httpClient = client
+	}
+}
+
+// WithVaultTimeout sets timeout for a single request including retries, default is 60 seconds
+func WithVaultTimeout(timeout time.Duration) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.timeout = timeout
+	}
+}
+
+// WithVaultRetry sets max number of retries and wait boundaries between them, default is 2 retries with 1-1.5 seconds wait
+func WithVaultRetry(maxRetries int, minWait, maxWait time.Duration) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.maxRetries = maxRetries
+		s.minRetryWait = minWait
+		s.maxRetryWait = maxWait
+	}
+}
+
+// WithVaultCheckRetry sets policy which decides if the request should be retried, default is retryablehttp.DefaultRetryPolicy
+func WithVaultCheckRetry(checkRetry retryablehttp.CheckRetry) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.checkRetry = checkRetry
+	}
+}
+
+// WithVaultBackoff sets wait calculation between retries, default is retryablehttp.LinearJitterBackoff
+func WithVaultBackoff(backoff retryablehttp.Backoff) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.backoff = backoff
+	}
+}
+
+// WithVaultRateLimit limits requests to rate per second with burst
+func WithVaultRateLimit(rate float64, burst int) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.rateLimit = rate
+		s.rateBurst = burst
+	}
+}
+
+/*
+NewVaultClient creates VaultClient configured with options
+If address is not passed with WithVaultAddress it is fetching from Environment using key VAULT_ADDR
+Server certificate is verified, TLS is configured with VAULT_CACERT, VAULT_CAPATH, VAULT_CLIENT_CERT, VAULT_CLIENT_KEY,
+VAULT_TLS_SERVER_NAME and VAULT_SKIP_VERIFY Environment variables or with corresponding options
+
+Example:
+cli, err := NewVaultClient(WithVaultToken(token), WithVaultTimeout(5 * time.Second))
+secret, err := cli.Read("secret/data/app")
+*/
+func NewVaultClient(opts ...vaultClientOption) (*VaultClient, error) {
+	tlsSettings, envErr := vaultTLSFromEnv()
+	if envErr != nil {
+		return nil, envErr
+	}
+	cacheSettings, envErr := vaultCacheFromEnv()
+	if envErr != nil {
+		return nil, envErr
+	}
+	s := &vaultSettings{
+		address:      os.Getenv(envVaultAddress),
+		timeout:      time.Second * 60,
+		maxRetries:   2,
+		minRetryWait: time.Millisecond * 1000,
+		maxRetryWait: time.Millisecond * 1500,
+		checkRetry:   retryablehttp.DefaultRetryPolicy,
+		backoff:      retryablehttp.LinearJitterBackoff,
+		tls:          tlsSettings,
+		cache:        cacheSettings,
+		namespace:    os.Getenv(envVaultNamespace),
+		recovery:     defaultVaultRecoveryInterval,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.addresses) > 0 {
+		s.address = s.addresses[0]
+	}
+	if s.address == "" {
+		return nil, errors.New("VAULT_ADDR Environment variable is required")
+	}
+	if s.httpClient == nil {
+		tlsConfig, tlsErr := buildTLSConfig(s.tls)
+		if tlsErr != nil {
+			return nil, tlsErr
+		}
+		s.httpClient = defaultVaultHTTPClient(tlsConfig)
+	}
+	httpClient := keepIdleConnections(s.httpClient, s.address)
+	cli, err := api.NewClient(&api.Config{
+		Address:      s.address,
+		HttpClient:   httpClient,
+		MinRetryWait: s.minRetryWait,
+		MaxRetryWait: s.maxRetryWait,
+		MaxRetries:   s.maxRetries,
+		Timeout:      s.timeout,
+		Backoff:      s.backoff,
+		CheckRetry:   s.checkRetry,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.rateLimit > 0 {
+		cli.SetLimiter(s.rateLimit, s.rateBurst)
+	}
+	headers := cli.Headers()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Del(vaultNamespaceHeader)
+	if s.namespace != "" {
+		headers.Set(vaultNamespaceHeader, s.namespace)
+	}
+	cli.SetHeaders(headers)
+	switch {
+	case s.auth != nil:
+		// token is obtained by auth method on the first request
+		cli.ClearToken()
+	case s.tokenSet:
+		cli.SetToken(s.token)
+	}
+	vc := &VaultClient{client: cli, auth: s.auth, mounts: &kvMounts{}, session: &vaultSession{}, namespace: s.namespace}
+	if len(s.addresses) > 1 {
+		vc.failover = newVaultFailover(cli, httpClient, s.addresses, s.recovery)
+	}
+	if s.rollbackGuard {
+		vc.guard = &versionGuard{seen: map[string]int{}}
+	}
+	if s.cache.path != "" {
+		cache, cacheErr := newVaultCache(s.cache)
+		if cacheErr != nil {
+			return nil, cacheErr
+		}
+		vc.cache = cache
+	}
+	return vc, nil
+}
+
+// SetToken replaces token used for requests, token is used until Vault rejects it
+func (vc *VaultClient) SetToken(token string) {
+	vc.session.mu.Lock()
+	defer vc.session.mu.Unlock()
+	vc.client.SetToken(token)
+	vc.session.tokenExpiry = time.Time{}
+}
+
+// Read reads secret stored under path, returns error wrapping ErrVaultPathNotFound if there is no secret
+func (vc *VaultClient) Read(path string) (*api.Secret, error) {
+	return vc.ReadCtx(context.Background(), path)
+}
+
+// ReadCtx reads secret like Read, ctx cancels the request including retries and waiting between them
+func (vc *VaultClient) ReadCtx(ctx context.Context, path string) (*api.Secret, error) {
+	return vc.read(ctx, path)
+}
+
+// ReadInto reads KV secret data stored under path and unmarshalls it to cfg, path syntax is the same as for ReadData
+// cfg should be passed as pointer
+func (vc *VaultClient) ReadInto(path string, cfg interface{}) error {
+	return vc.ReadIntoCtx(context.Background(), path, cfg)
+}
+
+// ReadIntoCtx reads KV secret data like ReadInto, ctx cancels the request
+func (vc *VaultClient) ReadIntoCtx(ctx context.Context, path string, cfg interface{}) error {
+	secretData, err := vc.readData(ctx, path)
+	if err != nil {
+		return err
+	}
+	data, mErr := json.Marshal(secretData)
+	if mErr != nil {
+		return mErr
+	}
+	return ParseBytes(data, JSON, cfg)
+}
+
+// List lists keys stored under path, returns error wrapping ErrVaultPathNotFound if there are no keys
+func (vc *VaultClient) List(path string) (*api.Secret, error) {
+	return vc.ListCtx(context.Background(), path)
+}
+
+// ListCtx lists keys like List, ctx cancels the request
+func (vc *VaultClient) ListCtx(ctx context.Context, path string) (*api.Secret, error) {
+	secret, err := vc.request(ctx, "LIST", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, vaultPathNotFound(path)
+	}
+	return secret, nil
+}
+
+// Write writes data under path, returned secret could be nil if Vault has nothing to respond
+func (vc *VaultClient) Write(path string, data map[string]interface{}) (*api.Secret, error) {
+	return vc.WriteCtx(context.Background(), path, data)
+}
+
+// WriteCtx writes data like Write, ctx cancels the request
+func (vc *VaultClient) WriteCtx(ctx context.Context, path string, data map[string]interface{}) (*api.Secret, error) {
+	return vc.request(ctx, http.MethodPut, path, data, nil)
+}
+
+func (vc *VaultClient) read(ctx context.Context, path string) (*api.Secret, error) {
+	secret, err := vc.cachedRead(ctx, path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, vaultPathNotFound(path)
+	}
+	return secret, nil
+}
+
+/*
+request is a single place where all requests to Vault are made
+Reads fail over to the next healthy address if multiple addresses are configured and Vault is unreachable
+*/
+func (vc *VaultClient) request(ctx context.Context, method, path string, body interface{}, params url.Values) (*api.Secret, error) {
+	return vc.withFailover(ctx, method, func() (*api.Secret, error) {
+		return vc.requestAuthenticated(ctx, method, path, body, params)
+	})
+}
+
+// withFailover calls send at the current address, reads are repeated at the next healthy address while Vault is unreachable
+func (vc *VaultClient) withFailover(ctx context.Context, method string, send func() (*api.Secret, error)) (*api.Secret, error) {
+	if vc.failover == nil {
+		return send()
+	}
+	vc.failover.recover(ctx)
+	for attempt := 0; ; attempt++ {
+		address := vc.client.Address()
+		secret, err := send()
+		if !isVaultUnreachable(err) || (method != http.MethodGet && method != "LIST") ||
+			attempt == len(vc.failover.addresses)-1 || !vc.failover.next(ctx, address) {
+			return secret, err
+		}
+	}
+}
+
+// sendWithToken writes body to path in namespace of vc like request, but with token instead of the client token
+// Client is not authenticated before the request, empty token means request is sent without token
+func (vc *VaultClient) sendWithToken(ctx context.Context, path, token string, body interface{}) (*api.Secret, error) {
+	return vc.withFailover(ctx, http.MethodPut, func() (*api.Secret, error) {
+		r, err := vc.newRequest(http.MethodPut, path, body, nil)
+		if err != nil {
+			return nil, err
+		}
+		r.ClientToken = token
+		return doVaultRequest(ctx, vc.client, r)
+	})
+}
+
+// requestAuthenticated authenticates client before the request if auth method is configured,
+// request is repeated once after re-authentication if Vault responds with 403
+func (vc *VaultClient) requestAuthenticated(ctx context.Context, method, path string, body interface{}, params url.Values) (*api.Secret, error) {
+	if err := vc.authenticate(ctx); err != nil {
+		return nil, err
+	}
+	token := vc.client.Token()
+	secret, err := vc.send(ctx, method, path, body, params)
+	if vc.auth != nil && isVaultPermissionDenied(err) {
+		if authErr := vc.reauthenticate(ctx, token); authErr != nil {
+			return nil, authErr
+		}
+		if vc.client.Token() != token {
+			return vc.send(ctx, method, path, body, params)
+		}
+	}
+	return secret, err
+}
+
+// send sends request built by newRequest with the client token
+func (vc *VaultClient) send(ctx context.Context, method, path string, body interface{}, params url.Values) (*api.Secret, error) {
+	r, err := vc.newRequest(method, path, body, params)
+	if err != nil {
+		return nil, err
+	}
+	return doVaultRequest(ctx, vc.client, r)
+}
+
+// newRequest builds request for path in namespace of vc, LIST method is sent as GET with list=true parameter for broader compatibility
+func (vc *VaultClient) newRequest(method, path string, body interface{}, params url.Values) (*api.Request, error) {
+	r := vc.client.NewRequest(method, "/v1/"+strings.TrimPrefix(path, "/"))
+	if method == "LIST" {
+		r.Method = http.MethodGet
+		r.Params.Set("list", "true")
+	}
+	for k, v := range params {
+		r.Params[k] = v
+	}
+	if r.Headers == nil {
+		r.Headers = http.Header{}
+	}
+	if method == http.MethodPatch {
+		r.Headers.Set("Content-Type", mergePatchContentType)
+	}
+	r.Headers.Del(vaultNamespaceHeader)
+	if vc.namespace != "" {
+		r.Headers.Set(vaultNamespaceHeader, vc.namespace)
+	}
+	if body != nil {
+		if err := r.SetJSONBody(body); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// doVaultRequest performs r and parses response, returning nil secret if Vault responds with 404 or without body
+func doVaultRequest(ctx context.Context, cli *api.Client, r *api.Request) (*api.Secret, error) {
+	resp, err := cli.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer drainBody(resp.Body)
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		secret, parseErr := api.ParseSecret(resp.Body)
+		switch {
+		case errors.Is(parseErr, io.EOF):
+			return nil, nil
+		case parseErr != nil:
+			return nil, parseErr
+		case secret != nil && (len(secret.Warnings) > 0 || len(secret.Data) > 0):
+			return secret, nil
+		default:
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	secret, parseErr := api.ParseSecret(resp.Body)
+	if errors.Is(parseErr, io.EOF) {
+		return nil, nil
+	}
+	return secret, parseErr
+}
+
+func isVaultPermissionDenied(err error) bool {
+	var respErr *api.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden
+}
+
+// drainBody reads body till the end before closing, so the connection could be reused
+func drainBody(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, body)
+	_ = body.Close()
+}
+
+// keepAliveTransport hides CloseIdleConnections of the wrapped transport
+// retryablehttp closes idle connections after every request which makes connection pooling useless
+type keepAliveTransport struct {
+	http.RoundTripper
+}
+
+func keepIdleConnections(client *http.Client, address string) *http.Client {
+	// api.NewClient configures unix sockets on *http.Transport directly
+	if strings.HasPrefix(address, "unix://") {
+		return client
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	wrapped := *client
+	wrapped.Transport = keepAliveTransport{RoundTripper: transport}
+	return &wrapped
+}
+
+func defaultVaultHTTPClient(tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			TLSClientConfig:       tlsConfig,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vielendanke/go-config/configtest"
+)
+
+type vaultClientTestConfig struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+func newVaultTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeVaultResponse(w http.ResponseWriter, data map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func newVaultTestClient(t *testing.T, srv *httptest.Server, opts ...vaultClientOption) *VaultClient {
+	opts = append([]vaultClientOption{
+		WithVaultAddress(srv.URL),
+		WithVaultToken(testToken),
+		WithVaultRetry(0, time.Millisecond, time.Millisecond),
+	}, opts...)
+	cli, err := NewVaultClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
+
+// newFakeVaultClient creates client of fake Vault authenticated with root token, failed requests are not retried
+func newFakeVaultClient(t *testing.T, vault *configtest.Vault, opts ...vaultClientOption) *VaultClient {
+	opts = append([]vaultClientOption{
+		WithVaultAddress(vault.URL()),
+		WithVaultToken(vault.RootToken()),
+		WithVaultRetry(0, time.Millisecond, time.Millisecond),
+	}, opts...)
+	cli, err := NewVaultClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
+
+func TestVaultClient_Read(t *testing.T) {
+	// prepare
+	var token string
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Vault-Token")
+		writeVaultResponse(w, map[string]interface{}{"data": map[string]interface{}{"user": "admin"}})
+	})
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.Read("/secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, testToken, token)
+	assert.Equal(t, map[string]interface{}{"user": "admin"}, secret.Data["data"])
+}
+
+func TestVaultClient_Read_NotFound(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+	})
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.Read("secret/data/missing")
+
+	// assertions
+	assert.Nil(t, secret)
+	assert.True(t, errors.Is(err, ErrVaultPathNotFound))
+}
+
+func TestVaultClient_ReadInto(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeVaultResponse(w, map[string]interface{}{"data": map[string]interface{}{"user": "admin", "password": "pass"}})
+	})
+	cli := newVaultTestClient(t, srv)
+	cfg := &vaultClientTestConfig{}
+
+	// make test
+	err := cli.ReadInto("secret/data/test", cfg)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", cfg.User)
+	assert.Equal(t, "pass", cfg.Password)
+}
+
+func TestVaultClient_List(t *testing.T) {
+	// prepare
+	var method, list string
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		method, list = r.Method, r.URL.Query().Get("list")
+		writeVaultResponse(w, map[string]interface{}{"keys": []string{"first", "second/"}})
+	})
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.List("secret/metadata")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, http.MethodGet, method)
+	assert.Equal(t, "true", list)
+	assert.Equal(t, []interface{}{"first", "second/"}, secret.Data["keys"])
+}
+
+func TestVaultClient_Write(t *testing.T) {
+	// prepare
+	var method, path string
+	var body map[string]interface{}
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.Write("secret/test", map[string]interface{}{"user": "admin"})
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Nil(t, secret)
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/v1/secret/test", path)
+	assert.Equal(t, map[string]interface{}{"user": "admin"}, body)
+}
+
+func TestVaultClient_ReusesClient(t *testing.T) {
+	// prepare
+	connections := map[string]bool{}
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		connections[r.RemoteAddr] = true
+		writeVaultResponse(w, map[string]interface{}{"data": map[string]interface{}{}})
+	})
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	for i := 0; i < 5; i++ {
+		_, err := cli.Read("secret/data/test")
+		assert.Nil(t, err)
+	}
+
+	// assertions
+	assert.Len(t, connections, 1)
+}
+
+func TestVaultClient_RateLimit(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	// make test
+	cli := newVaultTestClient(t, srv, WithVaultRateLimit(10, 1))
+
+	// assertions
+	assert.NotNil(t, cli.client.Limiter())
+}
+
+func TestNewVaultClient_Fails_AddressIsEmpty(t *testing.T) {
+	// prepare
+	os.Unsetenv(vaultAddr)
+
+	// make test
+	cli, err := NewVaultClient()
+
+	// assertions
+	assert.Nil(t, cli)
+	assert.NotNil(t, err)
+}
@@ -1,64 +1,78 @@
 package config
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"net"
-	"net/http"
 	"os"
-	"time"
 
-	"github.com/hashicorp/go-retryablehttp"
 	"github.com/hashicorp/vault/api"
 )
 
 /*
 FetchVaultSecret process fetching secret using incoming arguments (path, token)
-configVaultClient function is using inside
-Returning: map[string]interface{}, error
+VaultClient is created for each call, create it once with NewVaultClient for repeated fetching
+Returning: *api.Secret, error
 */
 func FetchVaultSecret(path, token string) (*api.Secret, error) {
-	cli, configErr := configVaultClient()
+	return FetchVaultSecretCtx(context.Background(), path, token)
+}
+
+// FetchVaultSecretCtx process fetching secret like FetchVaultSecret, ctx cancels the request including retries
+func FetchVaultSecretCtx(ctx context.Context, path, token string) (*api.Secret, error) {
+	cli, configErr := NewVaultClient(WithVaultAuth(TokenAuth(token)))
 	if configErr != nil {
 		return nil, configErr
 	}
-	cli.SetToken(token)
-	data, dataErr := cli.Logical().Read(path)
-	if dataErr != nil {
-		return nil, dataErr
-	}
-	if data == nil {
-		return nil, fmt.Errorf("path %s not found", path)
-	}
-	return data, nil
+	return cli.ReadCtx(ctx, path)
 }
 
 /*
-FetchVaultSecretEnv process fetching secret from vault using env variable VAULT_TOKEN for token and VAULT_SECRET_PATH for path to secret
-FetchVaultSecret function is using inside
+FetchVaultSecretEnv process fetching secret from vault using env variable VAULT_SECRET_PATH for path to secret
+Token is taken from the first source of DefaultTokenSources which has it: VAULT_TOKEN, VAULT_TOKEN_SINK file, ~/.vault-token
 Returning: *api.Secret, error
 */
 func FetchVaultSecretEnv() (*api.Secret, error) {
-	path, token, err := fetchVaultEnv()
+	return FetchVaultSecretEnvCtx(context.Background())
+}
+
+// FetchVaultSecretEnvCtx process fetching secret like FetchVaultSecretEnv, ctx cancels the request including retries
+func FetchVaultSecretEnvCtx(ctx context.Context) (*api.Secret, error) {
+	path, err := fetchVaultEnv()
 	if err != nil {
 		return nil, err
 	}
-	return FetchVaultSecret(path, token)
+	cli, configErr := NewVaultClient(WithVaultTokenChain())
+	if configErr != nil {
+		return nil, configErr
+	}
+	return cli.ReadCtx(ctx, path)
 }
 
 /*
-FetchBytesVaultSecretData process fetching secret bytes using incoming arguments (path, token)
-FetchVaultSecret function is using inside
+FetchBytesVaultSecretData process fetching KV secret data bytes using incoming arguments (path, token)
+KV v1 and v2 are supported, path could be logical ("secret/app") or KV v2 data path ("secret/data/app")
 Returning: []byte, error
 */
 func FetchBytesVaultSecretData(path, token string) ([]byte, error) {
-	secret, err := FetchVaultSecret(path, token)
+	return FetchBytesVaultSecretDataCtx(context.Background(), path, token)
+}
+
+// FetchBytesVaultSecretDataCtx process fetching KV secret data bytes like FetchBytesVaultSecretData, ctx cancels the request
+func FetchBytesVaultSecretDataCtx(ctx context.Context, path, token string) ([]byte, error) {
+	cli, configErr := NewVaultClient(WithVaultAuth(TokenAuth(token)))
+	if configErr != nil {
+		return nil, configErr
+	}
+	return fetchBytesVaultSecretData(ctx, cli, path)
+}
+
+func fetchBytesVaultSecretData(ctx context.Context, cli *VaultClient, path string) ([]byte, error) {
+	secretData, err := cli.ReadDataCtx(ctx, path)
 	if err != nil {
 		return nil, err
 	}
-	data, mErr := json.Marshal(secret.Data["data"])
+	data, mErr := json.Marshal(secretData)
 	if mErr != nil {
 		return nil, mErr
 	}
@@ -66,16 +80,25 @@ func FetchBytesVaultSecretData(path, token string) ([]byte, error) {
 }
 
 /*
-FetchBytesVaultSecretDataEnv process fetching secret bytes env variable VAULT_TOKEN for token and VAULT_SECRET_PATH for path to secret
-FetchBytesVaultSecretData function is using inside
+FetchBytesVaultSecretDataEnv process fetching secret bytes env variable VAULT_SECRET_PATH for path to secret
+Token is taken the same way as in FetchVaultSecretEnv
 Returning: []byte, error
 */
 func FetchBytesVaultSecretDataEnv() ([]byte, error) {
-	path, token, err := fetchVaultEnv()
+	return FetchBytesVaultSecretDataEnvCtx(context.Background())
+}
+
+// FetchBytesVaultSecretDataEnvCtx process fetching secret bytes like FetchBytesVaultSecretDataEnv, ctx cancels the request
+func FetchBytesVaultSecretDataEnvCtx(ctx context.Context) ([]byte, error) {
+	path, err := fetchVaultEnv()
 	if err != nil {
 		return nil, err
 	}
-	return FetchBytesVaultSecretData(path, token)
+	cli, configErr := NewVaultClient(WithVaultTokenChain())
+	if configErr != nil {
+		return nil, configErr
+	}
+	return fetchBytesVaultSecretData(ctx, cli, path)
 }
 
 /*
@@ -85,46 +108,16 @@ Environment key - VAULT_ADDR
 Returning api.Client, error
 */
 func configVaultClient() (*api.Client, error) {
-	vaultEnv := os.Getenv("VAULT_ADDR")
-	if vaultEnv == "" {
-		return nil, errors.New("VAULT_ADDR Environment variable is required")
-	}
-	cfg := &api.Config{
-		Address: vaultEnv,
-		HttpClient: &http.Client{
-			Transport: &http.Transport{
-				DialContext: (&net.Dialer{
-					Timeout:   30 * time.Second,
-					KeepAlive: 30 * time.Second,
-				}).DialContext,
-				MaxIdleConns:          100,
-				IdleConnTimeout:       90 * time.Second,
-				TLSHandshakeTimeout:   10 * time.Second,
-				ExpectContinueTimeout: 1 * time.Second,
-				TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-			},
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse
-			},
-		},
-		MinRetryWait: time.Millisecond * 1000,
-		MaxRetryWait: time.Millisecond * 1500,
-		MaxRetries:   2,
-		Timeout:      time.Second * 60,
-		Backoff:      retryablehttp.LinearJitterBackoff,
-		CheckRetry:   retryablehttp.DefaultRetryPolicy,
+	cli, err := NewVaultClient()
+	if err != nil {
+		return nil, err
 	}
-	return api.NewClient(cfg)
+	return cli.client, nil
 }
 
-func fetchVaultEnv() (path, token string, err error) {
-	token = os.Getenv("VAULT_TOKEN")
+func fetchVaultEnv() (path string, err error) {
 	path = os.Getenv("VAULT_SECRET_PATH")
 
-	if len(token) == 0 {
-		err = errors.New("token is not set in the environment variable VAULT_TOKEN")
-		return
-	}
 	if len(path) == 0 {
 		err = errors.New("path is not set in the enviromnent variable VAULT_SECRET_PATH")
 		return
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
-	"os"
+	"github.com/vielendanke/go-config/configtest"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -18,24 +21,37 @@ const (
 	vaultToken = "VAULT_TOKEN"
 )
 
+// newTestVault starts fake Vault with secret stored under testPath
+func newTestVault(t *testing.T) *configtest.Vault {
+	vault := configtest.NewVault(t, configtest.WithRootToken(testToken))
+	vault.WriteSecret("secret/test", map[string]interface{}{"key": "value"})
+	return vault
+}
+
+// isolateTokenChain makes default token chain ignore tokens of the machine running tests, e.g. ~/.vault-token or Vault Agent
+func isolateTokenChain(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(vaultToken, "")
+	t.Setenv(envVaultTokenSink, "")
+	t.Setenv("VAULT_AGENT_ADDR", "")
+}
+
 func TestConfigVaultClient(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultAddr, testHost)
+	t.Setenv(vaultAddr, testHost)
 
 	// make test
 	cli, cliErr := configVaultClient()
 
-	// clean
-	rErr := os.Unsetenv(vaultAddr)
-
 	// assertions
 	assert.NotNil(t, cli)
-	assert.Nil(t, setEnvErr)
 	assert.Nil(t, cliErr)
-	assert.Nil(t, rErr)
 }
 
 func TestConfigVaultClient_Fails_EnvDoesNotExists(t *testing.T) {
+	// prepare
+	t.Setenv(vaultAddr, "")
+
 	// make test
 	cli, cliErr := configVaultClient()
 
@@ -46,7 +62,8 @@ func TestConfigVaultClient_Fails_EnvDoesNotExists(t *testing.T) {
 
 func TestFetchVaultSecret(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultAddr, testHost)
+	vault := newTestVault(t)
+	t.Setenv(vaultAddr, vault.URL())
 
 	// make test
 	cfg, cfgFetchErr := FetchVaultSecret(testPath, testToken)
@@ -54,12 +71,11 @@ func TestFetchVaultSecret(t *testing.T) {
 	// assertions
 	assert.Nil(t, cfgFetchErr)
 	assert.NotNil(t, cfg)
-	assert.Nil(t, setEnvErr)
 }
 
 func TestFetchVaultSecret_TokenIsEmpty(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultAddr, testHost)
+	t.Setenv(vaultAddr, testHost)
 
 	// make test
 	cfg, cfgFetchErr := FetchVaultSecret(testPath, "")
@@ -67,95 +83,108 @@ func TestFetchVaultSecret_TokenIsEmpty(t *testing.T) {
 	// assertions
 	assert.Nil(t, cfg)
 	assert.NotNil(t, cfgFetchErr)
-	assert.Nil(t, setEnvErr)
 }
 
 func TestFetchVaultSecret_PathIsEmpty(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultAddr, testHost)
+	vault := newTestVault(t)
+	t.Setenv(vaultAddr, vault.URL())
 
 	// make test
-	cfg, cfgFetchErr := FetchVaultSecret(testPath, "")
+	cfg, cfgFetchErr := FetchVaultSecret("", testToken)
 
 	// assertions
 	assert.Nil(t, cfg)
-	assert.NotNil(t, cfgFetchErr)
-	assert.Nil(t, setEnvErr)
+	assert.True(t, errors.Is(cfgFetchErr, ErrVaultPathNotFound))
 }
 
 func TestFetchVaultSecretEnv(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultAddr, testHost)
-	setEnvErr = os.Setenv(vaultToken, testToken)
-	setEnvErr = os.Setenv(vaultSecretPath, testPath)
+	vault := newTestVault(t)
+	t.Setenv(vaultAddr, vault.URL())
+	t.Setenv(vaultToken, testToken)
+	t.Setenv(vaultSecretPath, testPath)
 
 	// make test
 	secret, fetchErr := FetchVaultSecretEnv()
 
-	// clean
-	setEnvErr = os.Unsetenv(vaultAddr)
-	setEnvErr = os.Unsetenv(vaultToken)
-	setEnvErr = os.Unsetenv(vaultSecretPath)
-
 	// assertions
 	assert.Nil(t, fetchErr)
 	assert.NotNil(t, secret)
-	assert.Nil(t, setEnvErr)
 }
 
 func TestFetchVaultSecretEnv_VaultAddrIsEmpty(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultToken, testToken)
-	setEnvErr = os.Setenv(vaultSecretPath, testPath)
+	t.Setenv(vaultAddr, "")
+	t.Setenv(vaultToken, testToken)
+	t.Setenv(vaultSecretPath, testPath)
 
 	// make test
 	secret, fetchErr := FetchVaultSecretEnv()
 
-	// clean
-	setEnvErr = os.Unsetenv(vaultToken)
-	setEnvErr = os.Unsetenv(vaultSecretPath)
-
 	// assertions
 	assert.NotNil(t, fetchErr)
 	assert.Nil(t, secret)
-	assert.Nil(t, setEnvErr)
 }
 
 func TestFetchVaultSecretEnv_VaultTokenIsEmpty(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultAddr, testHost)
-	setEnvErr = os.Setenv(vaultSecretPath, testPath)
+	isolateTokenChain(t)
+	vault := newTestVault(t)
+	t.Setenv(vaultAddr, vault.URL())
+	t.Setenv(vaultSecretPath, testPath)
 
 	// make test
 	secret, fetchErr := FetchVaultSecretEnv()
 
-	// clean
-	setEnvErr = os.Unsetenv(vaultAddr)
-	setEnvErr = os.Unsetenv(vaultSecretPath)
+	// assertions
+	assert.True(t, errors.Is(fetchErr, ErrVaultTokenNotFound))
+	assert.Nil(t, secret)
+	assert.Equal(t, 0, vault.Requests())
+}
+
+func TestFetchVaultSecretEnv_Fails_ListsTriedTokenSources(t *testing.T) {
+	// prepare
+	isolateTokenChain(t)
+	vault := newTestVault(t)
+	sink := filepath.Join(t.TempDir(), "sink")
+	t.Setenv(vaultAddr, vault.URL())
+	t.Setenv(vaultSecretPath, testPath)
+	t.Setenv(envVaultTokenSink, sink)
+
+	// make test
+	secret, fetchErr := FetchVaultSecretEnv()
 
 	// assertions
-	assert.NotNil(t, fetchErr)
 	assert.Nil(t, secret)
-	assert.Nil(t, setEnvErr)
+	assert.True(t, errors.Is(fetchErr, ErrVaultTokenNotFound))
+	tried := []string{"environment variable VAULT_TOKEN (empty)", "token file " + sink + " (", "~/.vault-token ("}
+	last := -1
+	for _, source := range tried {
+		idx := strings.Index(fetchErr.Error(), source)
+		assert.Greater(t, idx, last, source)
+		last = idx
+	}
+	assert.Equal(t, 0, vault.Requests())
 }
 
 func TestFetchBytesVaultSecretData(t *testing.T) {
 	// prepare
-	envErr := os.Setenv(vaultAddr, testHost)
+	vault := newTestVault(t)
+	t.Setenv(vaultAddr, vault.URL())
 
 	// make test
 	data, err := FetchBytesVaultSecretData(testPath, testToken)
 
-	// clean
-	envErr = os.Unsetenv(vaultAddr)
-
 	// assertions
 	assert.Nil(t, err)
 	assert.NotNil(t, data)
-	assert.Nil(t, envErr)
 }
 
 func TestFetchBytesVaultSecretData_VaultAddrEnvIsEmpty(t *testing.T) {
+	// prepare
+	t.Setenv(vaultAddr, "")
+
 	// make test
 	data, err := FetchBytesVaultSecretData(testPath, testToken)
 
@@ -184,74 +213,61 @@ func TestFetchBytesVaultSecretData_TokenIsEmpty(t *testing.T) {
 
 func TestFetchBytesVaultSecretDataEnv(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultAddr, testHost)
-	setEnvErr = os.Setenv(vaultToken, testToken)
-	setEnvErr = os.Setenv(vaultSecretPath, testPath)
+	vault := newTestVault(t)
+	t.Setenv(vaultAddr, vault.URL())
+	t.Setenv(vaultToken, testToken)
+	t.Setenv(vaultSecretPath, testPath)
 
 	// make test
 	secret, fetchErr := FetchBytesVaultSecretDataEnv()
 
-	// clean
-	setEnvErr = os.Unsetenv(vaultAddr)
-	setEnvErr = os.Unsetenv(vaultToken)
-	setEnvErr = os.Unsetenv(vaultSecretPath)
-
 	// assertions
-	assert.Nil(t, setEnvErr)
 	assert.Nil(t, fetchErr)
 	assert.NotNil(t, secret)
 }
 
 func TestFetchBytesVaultSecretDataEnv_VaultAddrEnvIsEmpty(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultToken, testToken)
-	setEnvErr = os.Setenv(vaultSecretPath, testPath)
+	t.Setenv(vaultAddr, "")
+	t.Setenv(vaultToken, testToken)
+	t.Setenv(vaultSecretPath, testPath)
 
 	// make test
 	secret, fetchErr := FetchBytesVaultSecretDataEnv()
 
-	// clean
-	setEnvErr = os.Unsetenv(vaultToken)
-	setEnvErr = os.Unsetenv(vaultSecretPath)
-
 	// assertions
-	assert.Nil(t, setEnvErr)
 	assert.NotNil(t, fetchErr)
 	assert.Nil(t, secret)
 }
 
 func TestFetchBytesVaultSecretDataEnv_VaultTokenEnvIsEmpty(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultAddr, testHost)
-	setEnvErr = os.Setenv(vaultSecretPath, testPath)
+	isolateTokenChain(t)
+	vault := newTestVault(t)
+	t.Setenv(vaultAddr, vault.URL())
+	t.Setenv(vaultSecretPath, testPath)
 
 	// make test
 	secret, fetchErr := FetchBytesVaultSecretDataEnv()
 
-	// clean
-	setEnvErr = os.Unsetenv(vaultAddr)
-	setEnvErr = os.Unsetenv(vaultSecretPath)
-
 	// assertions
-	assert.Nil(t, setEnvErr)
-	assert.NotNil(t, fetchErr)
+	assert.True(t, errors.Is(fetchErr, ErrVaultTokenNotFound))
 	assert.Nil(t, secret)
+	assert.Equal(t, 0, vault.Requests())
 }
 
 func TestFetchBytesVaultSecretDataEnv_VaultSecretPathEnvIsEmpty(t *testing.T) {
 	// prepare
-	setEnvErr := os.Setenv(vaultAddr, testHost)
-	setEnvErr = os.Setenv(vaultToken, testToken)
+	vault := newTestVault(t)
+	t.Setenv(vaultAddr, vault.URL())
+	t.Setenv(vaultToken, testToken)
+	t.Setenv(vaultSecretPath, "")
 
 	// make test
 	secret, fetchErr := FetchBytesVaultSecretDataEnv()
 
-	// clean
-	setEnvErr = os.Unsetenv(vaultAddr)
-	setEnvErr = os.Unsetenv(vaultToken)
-
 	// assertions
-	assert.Nil(t, setEnvErr)
-	assert.NotNil(t, fetchErr)
+	assert.EqualError(t, fetchErr, "path is not set in the enviromnent variable VAULT_SECRET_PATH")
 	assert.Nil(t, secret)
+	assert.Equal(t, 0, vault.Requests())
 }
\ No newline at end of file
@@ -0,0 +1,391 @@
+package config
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDatabaseMount = "database"
+)
+
+// DBCredentials are dynamic database credentials issued by Vault
+type DBCredentials struct {
+	Username string
+	Password string
+	LeaseID  string
+	// ExpiresAt is the time when lease expires if it is not renewed
+	ExpiresAt time.Time
+}
+
+/*
+VaultDBCredentials reads dynamic credentials from database/creds/<role>, renews their lease
+and fetches new credentials before lease reaches its max TTL
+Connector returns driver.Connector which opens new connections with the current credentials,
+so database/sql pool picks up new credentials without a restart
+Events are sent to the channel returned by Events and to the callback set with WithDBCallback,
+the channel is closed when Run returns, so Run should be called only once
+
+Important note: connections opened with old credentials keep working until their lease expires,
+set sql.DB SetConnMaxLifetime shorter than lease TTL to replace them in time,
+use WithDBRevokeGrace to revoke old lease earlier once pooled connections were replaced
+
+Example:
+creds := NewVaultDBCredentials(cli, "app")
+go creds.Run(ctx)
+db := sql.OpenDB(creds.Connector(&pq.Driver{}, func(c DBCredentials) string {
+   return fmt.Sprintf("postgres://%s:%s@db:5432/app", c.Username, c.Password)
+}))
+*/
+type VaultDBCredentials struct {
+	vc            *VaultClient
+	mount         string
+	role          string
+	minTTL        time.Duration
+	retryInterval time.Duration
+	events        chan VaultEvent
+	callback      func(VaultEvent)
+	// eventsMu guards events against sending after they were closed by Run, Credentials could fetch later
+	eventsMu     sync.Mutex
+	eventsClosed bool
+
+	fetchMu sync.Mutex
+	mu      sync.RWMutex
+	creds   *DBCredentials
+	// leaseTTL is TTL of the lease when credentials were issued, renewal returning less means max TTL is reached
+	leaseTTL time.Duration
+	due      time.Time
+	rotate   bool
+	// revokes are leases of rotated credentials waiting for revocation, see WithDBRevokeGrace
+	revokeGrace time.Duration
+	revokes     []dbRevoke
+}
+
+// dbRevoke is lease of rotated credentials which is revoked at time at
+type dbRevoke struct {
+	leaseID string
+	at      time.Time
+}
+
+type dbCredentialsOption func(c *VaultDBCredentials)
+
+// WithDBMount sets mount path of database secrets engine, default is "database"
+func WithDBMount(mount string) dbCredentialsOption {
+	return func(c *VaultDBCredentials) {
+		c.mount = mount
+	}
+}
+
+// WithDBMinTTL sets lease TTL below which credentials are not renewed but fetched again, default is 10 seconds
+func WithDBMinTTL(ttl time.Duration) dbCredentialsOption {
+	return func(c *VaultDBCredentials) {
+		c.minTTL = ttl
+	}
+}
+
+// WithDBRetryInterval sets wait before retry of failed renewal or fetch, default is 5 seconds
+func WithDBRetryInterval(interval time.Duration) dbCredentialsOption {
+	return func(c *VaultDBCredentials) {
+		c.retryInterval = interval
+	}
+}
+
+// WithDBRevokeGrace makes lease of rotated credentials revoked grace after rotation instead of letting it expire,
+// grace should be longer than sql.DB ConnMaxLifetime, so pooled connections are replaced before, default is 0 (not revoked)
+func WithDBRevokeGrace(grace time.Duration) dbCredentialsOption {
+	return func(c *VaultDBCredentials) {
+		c.revokeGrace = grace
+	}
+}
+
+// WithDBCallback sets function called synchronously for every event
+func WithDBCallback(callback func(VaultEvent)) dbCredentialsOption {
+	return func(c *VaultDBCredentials) {
+		c.callback = callback
+	}
+}
+
+// NewVaultDBCredentials creates dynamic credentials of role, credentials are fetched by Credentials or Run
+func NewVaultDBCredentials(vc *VaultClient, role string, opts ...dbCredentialsOption) *VaultDBCredentials {
+	c := &VaultDBCredentials{
+		vc:            vc,
+		mount:         defaultDatabaseMount,
+		role:          role,
+		minTTL:        defaultWatcherMinTTL,
+		retryInterval: defaultWatcherRetryInterval,
+		events:        make(chan VaultEvent, watcherEventsBuffer),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Events returns channel with events, events are dropped if channel buffer is full, channel is closed when Run returns
+func (c *VaultDBCredentials) Events() <-chan VaultEvent {
+	return c.events
+}
+
+// Credentials returns the current credentials, they are fetched on the first call if Run was not called
+// It is safe to call Credentials concurrently with Run, e.g. from connection pool
+func (c *VaultDBCredentials) Credentials(ctx context.Context) (DBCredentials, error) {
+	if creds, ok := c.current(); ok {
+		return creds, nil
+	}
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	if creds, ok := c.current(); ok {
+		return creds, nil
+	}
+	if err := c.fetch(ctx); err != nil {
+		return DBCredentials{}, err
+	}
+	creds, _ := c.current()
+	return creds, nil
+}
+
+// Run renews lease and rotates credentials until ctx is done, credentials are fetched first if they were not fetched yet
+func (c *VaultDBCredentials) Run(ctx context.Context) error {
+	defer c.closeEvents()
+	if _, err := c.Credentials(ctx); err != nil {
+		c.emit(VaultEvent{Type: WatcherError, Err: err})
+		c.setDue(time.Now().Add(c.retryInterval), true)
+	}
+	for {
+		timer := time.NewTimer(time.Until(c.nextWakeup()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			c.revokeDue(ctx)
+			if !time.Now().Before(c.nextDue()) {
+				c.renewOrRotate(ctx)
+			}
+		}
+	}
+}
+
+// Connector returns driver.Connector opening connections with the current credentials, dsn builds data source name from them
+// If drv implements driver.DriverContext, its connector is used to open connections
+func (c *VaultDBCredentials) Connector(drv driver.Driver, dsn func(DBCredentials) string) driver.Connector {
+	return &dbConnector{creds: c, driver: drv, dsn: dsn}
+}
+
+func (c *VaultDBCredentials) renewOrRotate(ctx context.Context) {
+	c.mu.RLock()
+	rotate := c.rotate || c.creds == nil
+	c.mu.RUnlock()
+
+	if !rotate && c.renew(ctx) {
+		return
+	}
+	c.fetchMu.Lock()
+	err := c.fetch(ctx)
+	c.fetchMu.Unlock()
+	if err != nil {
+		c.emit(VaultEvent{Type: WatcherError, Err: err})
+		c.setDue(time.Now().Add(c.retryInterval), true)
+	}
+}
+
+// renew extends lease, returns false if credentials should be fetched again
+func (c *VaultDBCredentials) renew(ctx context.Context) bool {
+	c.mu.RLock()
+	leaseID, leaseTTL := c.creds.LeaseID, c.leaseTTL
+	c.mu.RUnlock()
+
+	body := map[string]interface{}{"lease_id": leaseID, "increment": int(leaseTTL.Seconds())}
+	secret, err := c.vc.request(ctx, http.MethodPut, "sys/leases/renew", body, nil)
+	if isVaultUnreachable(err) {
+		c.mu.RLock()
+		expiresAt := c.creds.ExpiresAt
+		c.mu.RUnlock()
+		// lease is still valid, so credentials are kept until Vault is reachable again
+		if time.Now().Before(expiresAt) {
+			c.emit(VaultEvent{Type: WatcherError, LeaseID: leaseID, Err: err})
+			c.setDue(time.Now().Add(c.retryInterval), false)
+			return true
+		}
+	}
+	if err != nil || secret == nil || !secret.Renewable {
+		c.emit(VaultEvent{Type: LeaseExpired, LeaseID: leaseID, Err: err})
+		return false
+	}
+	ttl := time.Duration(secret.LeaseDuration) * time.Second
+	if ttl < c.minTTL {
+		c.emit(VaultEvent{Type: LeaseExpired, LeaseID: leaseID})
+		return false
+	}
+
+	c.mu.Lock()
+	if c.creds != nil && c.creds.LeaseID == leaseID {
+		// credentials are replaced instead of being changed in place, so copies taken by readers stay consistent
+		renewed := *c.creds
+		renewed.ExpiresAt = time.Now().Add(ttl)
+		c.creds = &renewed
+	}
+	c.mu.Unlock()
+	// lease was renewed for less than requested, so max TTL is close, credentials are fetched again next time
+	c.setDue(renewAt(secret.LeaseDuration), ttl < leaseTTL)
+	c.emit(VaultEvent{Type: LeaseRenewed, LeaseID: leaseID, TTL: ttl})
+	return true
+}
+
+// fetch reads new credentials, caller should hold fetchMu
+func (c *VaultDBCredentials) fetch(ctx context.Context) error {
+	path := c.mount + "/creds/" + c.role
+	secret, err := c.vc.request(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return fmt.Errorf("%w: %s", ErrVaultPathNotFound, path)
+	}
+	username, _ := secret.Data["username"].(string)
+	password, _ := secret.Data["password"].(string)
+	if username == "" || password == "" {
+		return errors.New("database credentials response does not contain username or password")
+	}
+	ttl := time.Duration(secret.LeaseDuration) * time.Second
+	creds := &DBCredentials{Username: username, Password: password, LeaseID: secret.LeaseID, ExpiresAt: time.Now().Add(ttl)}
+
+	c.mu.Lock()
+	previous := c.creds
+	c.creds = creds
+	c.leaseTTL = ttl
+	if previous != nil && previous.LeaseID != "" && c.revokeGrace > 0 {
+		if at := time.Now().Add(c.revokeGrace); at.Before(previous.ExpiresAt) {
+			c.revokes = append(c.revokes, dbRevoke{leaseID: previous.LeaseID, at: at})
+		}
+	}
+	c.mu.Unlock()
+
+	switch {
+	case ttl == 0:
+		// credentials without lease never expire
+		c.setDue(time.Now().Add(time.Hour*24*365), false)
+	case !secret.Renewable:
+		c.setDue(renewAt(secret.LeaseDuration), true)
+	default:
+		c.setDue(renewAt(secret.LeaseDuration), false)
+	}
+	if previous != nil {
+		c.emit(VaultEvent{Type: CredentialsRotated, LeaseID: creds.LeaseID, TTL: ttl})
+	}
+	return nil
+}
+
+// revokeDue revokes leases of rotated credentials which grace period is over, failed revocation is not retried,
+// lease expires on its own then
+func (c *VaultDBCredentials) revokeDue(ctx context.Context) {
+	c.mu.Lock()
+	var due []string
+	pending := c.revokes[:0]
+	for _, r := range c.revokes {
+		if time.Now().Before(r.at) {
+			pending = append(pending, r)
+			continue
+		}
+		due = append(due, r.leaseID)
+	}
+	c.revokes = pending
+	c.mu.Unlock()
+
+	for _, leaseID := range due {
+		_, err := c.vc.request(ctx, http.MethodPut, "sys/leases/revoke", map[string]interface{}{"lease_id": leaseID}, nil)
+		if err != nil {
+			c.emit(VaultEvent{Type: WatcherError, LeaseID: leaseID, Err: err})
+		}
+	}
+}
+
+// current returns copy of the current credentials, ok is false if they were not fetched yet
+func (c *VaultDBCredentials) current() (DBCredentials, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.creds == nil {
+		return DBCredentials{}, false
+	}
+	return *c.creds, true
+}
+
+func (c *VaultDBCredentials) nextDue() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.due
+}
+
+// nextWakeup returns the earliest of renewal time and revocation times
+func (c *VaultDBCredentials) nextWakeup() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	wakeup := c.due
+	for _, r := range c.revokes {
+		if r.at.Before(wakeup) {
+			wakeup = r.at
+		}
+	}
+	return wakeup
+}
+
+func (c *VaultDBCredentials) setDue(due time.Time, rotate bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.due = due
+	c.rotate = rotate
+}
+
+func (c *VaultDBCredentials) emit(e VaultEvent) {
+	if c.callback != nil {
+		c.callback(e)
+	}
+	c.eventsMu.Lock()
+	defer c.eventsMu.Unlock()
+	if c.eventsClosed {
+		return
+	}
+	select {
+	case c.events <- e:
+	default:
+	}
+}
+
+func (c *VaultDBCredentials) closeEvents() {
+	c.eventsMu.Lock()
+	defer c.eventsMu.Unlock()
+	c.eventsClosed = true
+	close(c.events)
+}
+
+// dbConnector opens connections with the current credentials
+type dbConnector struct {
+	creds  *VaultDBCredentials
+	driver driver.Driver
+	dsn    func(DBCredentials) string
+}
+
+func (c *dbConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	creds, err := c.creds.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dsn := c.dsn(creds)
+	if drvCtx, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := drvCtx.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return c.driver.Open(dsn)
+}
+
+func (c *dbConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vielendanke/go-config/configtest"
+)
+
+// dbTestServer issues credentials with lease of leaseTTL seconds, renewal is capped with maxTTL seconds
+type dbTestServer struct {
+	leaseTTL int
+	maxTTL   int
+
+	mu      sync.Mutex
+	issued  int
+	renews  []map[string]interface{}
+	revokes []string
+}
+
+func (s *dbTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/v1/database/creds/app":
+		s.issued++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       fmt.Sprintf("database/creds/app/%d", s.issued),
+			"lease_duration": s.leaseTTL,
+			"renewable":      true,
+			"data":           map[string]interface{}{"username": fmt.Sprintf("user-%d", s.issued), "password": "pass"},
+		})
+	case "/v1/sys/leases/renew":
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.renews = append(s.renews, body)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       body["lease_id"],
+			"lease_duration": s.maxTTL,
+			"renewable":      true,
+		})
+	case "/v1/sys/leases/revoke":
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.revokes = append(s.revokes, fmt.Sprint(body["lease_id"]))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type dbTestDriver struct {
+	mu   sync.Mutex
+	dsns []string
+}
+
+func (d *dbTestDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dsns = append(d.dsns, dsn)
+	return dbTestConn{}, nil
+}
+
+type dbTestConn struct{}
+
+func (dbTestConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (dbTestConn) Close() error {
+	return nil
+}
+
+func (dbTestConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestVaultDBCredentials_Connector(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&dbTestServer{leaseTTL: 3600, maxTTL: 3600}).handler)
+	creds := NewVaultDBCredentials(newVaultTestClient(t, srv), "app")
+	drv := &dbTestDriver{}
+	db := sql.OpenDB(creds.Connector(drv, func(c DBCredentials) string {
+		return fmt.Sprintf("postgres://%s:%s@db/app", c.Username, c.Password)
+	}))
+	defer db.Close()
+
+	// make test
+	conn, err := db.Conn(context.Background())
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Nil(t, conn.Close())
+	assert.Equal(t, []string{"postgres://user-1:pass@db/app"}, drv.dsns)
+	current, err := creds.Credentials(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "database/creds/app/1", current.LeaseID)
+	assert.True(t, current.ExpiresAt.After(time.Now().Add(time.Minute)))
+}
+
+func TestVaultDBCredentials_Run_RenewsAndRotates(t *testing.T) {
+	// prepare
+	fake := &dbTestServer{leaseTTL: 2, maxTTL: 1}
+	srv := newVaultTestServer(t, fake.handler)
+	creds := NewVaultDBCredentials(newVaultTestClient(t, srv), "app", WithDBMinTTL(0))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// make test
+	go func() {
+		_ = creds.Run(ctx)
+	}()
+
+	// assertions
+	renewed := <-creds.Events()
+	assert.Equal(t, LeaseRenewed, renewed.Type)
+	assert.Equal(t, "database/creds/app/1", renewed.LeaseID)
+	assert.Equal(t, time.Second, renewed.TTL)
+	rotated := <-creds.Events()
+	assert.Equal(t, CredentialsRotated, rotated.Type)
+	assert.Equal(t, "database/creds/app/2", rotated.LeaseID)
+	current, err := creds.Credentials(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-2", current.Username)
+	fake.mu.Lock()
+	assert.Equal(t, []map[string]interface{}{{"lease_id": "database/creds/app/1", "increment": float64(2)}}, fake.renews)
+	assert.Empty(t, fake.revokes)
+	fake.mu.Unlock()
+}
+
+func TestVaultDBCredentials_Run_KeepsRotatedLease(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteLeasedSecret("database/creds/app", map[string]interface{}{"username": "app", "password": "pass"}, 2*time.Second, false)
+	creds := NewVaultDBCredentials(newFakeVaultClient(t, vault), "app", WithDBMinTTL(0))
+	first, firstErr := creds.Credentials(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// make test
+	go func() {
+		_ = creds.Run(ctx)
+	}()
+	rotated := <-creds.Events()
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Equal(t, CredentialsRotated, rotated.Type)
+	assert.NotEqual(t, first.LeaseID, rotated.LeaseID)
+	assert.ElementsMatch(t, []string{first.LeaseID, rotated.LeaseID}, vault.Leases())
+}
+
+func TestVaultDBCredentials_Run_RevokesRotatedLeaseAfterGrace(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteLeasedSecret("database/creds/app", map[string]interface{}{"username": "app", "password": "pass"}, 2*time.Second, false)
+	creds := NewVaultDBCredentials(newFakeVaultClient(t, vault), "app", WithDBMinTTL(0), WithDBRevokeGrace(100*time.Millisecond))
+	first, firstErr := creds.Credentials(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// make test
+	go func() {
+		_ = creds.Run(ctx)
+	}()
+	rotated := <-creds.Events()
+	leasesAfterRotation := vault.Leases()
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Equal(t, CredentialsRotated, rotated.Type)
+	assert.ElementsMatch(t, []string{first.LeaseID, rotated.LeaseID}, leasesAfterRotation)
+	assert.Eventually(t, func() bool {
+		leases := vault.Leases()
+		return len(leases) == 1 && leases[0] == rotated.LeaseID
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestVaultDBCredentials_Run_RetriesRenewalWhenVaultIsUnreachable(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteLeasedSecret("database/creds/app", map[string]interface{}{"username": "app", "password": "pass"}, time.Second, true)
+	creds := NewVaultDBCredentials(newFakeVaultClient(t, vault), "app", WithDBMinTTL(0), WithDBRetryInterval(50*time.Millisecond))
+	first, firstErr := creds.Credentials(context.Background())
+	vault.FailNext(1, http.StatusServiceUnavailable)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// make test
+	go func() {
+		_ = creds.Run(ctx)
+	}()
+	failed := <-creds.Events()
+	renewed := <-creds.Events()
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Equal(t, WatcherError, failed.Type)
+	assert.Equal(t, first.LeaseID, failed.LeaseID)
+	assert.Equal(t, LeaseRenewed, renewed.Type)
+	assert.Equal(t, first.LeaseID, renewed.LeaseID)
+	current, err := creds.Credentials(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, first.LeaseID, current.LeaseID)
+}
+
+func TestVaultDBCredentials_Run_ClosesEvents(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteLeasedSecret("database/creds/app", map[string]interface{}{"username": "app", "password": "pass"}, time.Hour, true)
+	creds := NewVaultDBCredentials(newFakeVaultClient(t, vault), "missing", WithDBRetryInterval(time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+
+	// make test
+	go func() {
+		done <- creds.Run(ctx)
+	}()
+	var events []VaultEventType
+	for e := range creds.Events() {
+		events = append(events, e.Type)
+		cancel()
+	}
+	_, credsErr := creds.Credentials(context.Background())
+
+	// assertions
+	assert.Equal(t, []VaultEventType{WatcherError}, events)
+	assert.True(t, errors.Is(<-done, context.Canceled))
+	assert.True(t, errors.Is(credsErr, ErrVaultPathNotFound))
+}
+
+func TestVaultDBCredentials_Credentials_ConcurrentWithRenewal(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteLeasedSecret("database/creds/app", map[string]interface{}{"username": "app", "password": "pass"}, time.Second, true)
+	creds := NewVaultDBCredentials(newFakeVaultClient(t, vault), "app", WithDBMinTTL(0))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// make test
+	go func() {
+		_ = creds.Run(ctx)
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					_, _ = creds.Credentials(ctx)
+				}
+			}
+		}()
+	}
+	renewed := <-creds.Events()
+	close(stop)
+	wg.Wait()
+
+	// assertions
+	assert.Equal(t, LeaseRenewed, renewed.Type)
+	current, err := creds.Credentials(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, renewed.LeaseID, current.LeaseID)
+	assert.True(t, current.ExpiresAt.After(time.Now()))
+}
+
+func TestVaultDBCredentials_Fails_RoleNotFound(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&dbTestServer{leaseTTL: 2, maxTTL: 1}).handler)
+	creds := NewVaultDBCredentials(newVaultTestClient(t, srv), "missing")
+
+	// make test
+	_, err := creds.Credentials(context.Background())
+
+	// assertions
+	assert.True(t, errors.Is(err, ErrVaultPathNotFound))
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultVaultRecoveryInterval = time.Minute
+	vaultHealthTimeout           = 5 * time.Second
+)
+
+/*
+WithVaultAddresses sets ordered list of Vault addresses, the first one is preferred
+If Vault at the current address is unreachable, reads (GET and LIST requests) fail over to the next address
+which reports healthy status on sys/health, writes are not retried but are sent to the current address as well
+Client stays at the address it failed over to, preferred addresses are checked again each recovery interval,
+see WithVaultFailoverRecovery
+
+Example:
+cli, err := NewVaultClient(WithVaultAddresses("https://vault.dc1:8200", "https://vault-dr.dc2:8200"))
+*/
+func WithVaultAddresses(addresses ...string) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.addresses = addresses
+	}
+}
+
+// WithVaultFailoverRecovery sets how often preferred addresses are checked after fail over, default is 1 minute
+// 0 disables recovery, client stays at the address it failed over to
+func WithVaultFailoverRecovery(interval time.Duration) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.recovery = interval
+	}
+}
+
+// Address returns the address requests are sent to
+func (vc *VaultClient) Address() string {
+	return vc.client.Address()
+}
+
+// vaultFailover switches address of the client between clusters
+type vaultFailover struct {
+	client     *api.Client
+	httpClient *http.Client
+	addresses  []string
+	recovery   time.Duration
+
+	mu        sync.Mutex
+	current   int
+	lastCheck time.Time
+}
+
+func newVaultFailover(cli *api.Client, httpClient *http.Client, addresses []string, recovery time.Duration) *vaultFailover {
+	return &vaultFailover{client: cli, httpClient: httpClient, addresses: addresses, recovery: recovery}
+}
+
+// next switches client to the next healthy address after failed one, returns false if there is no healthy address
+func (f *vaultFailover) next(ctx context.Context, failed string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.addresses[f.current] != failed {
+		// concurrent request has already switched the address
+		return true
+	}
+	for i := 1; i < len(f.addresses); i++ {
+		idx := (f.current + i) % len(f.addresses)
+		if f.healthy(ctx, f.addresses[idx]) {
+			f.switchTo(idx)
+			return true
+		}
+	}
+	return false
+}
+
+// recover switches client back to the most preferred healthy address, it is done at most once per recovery interval
+func (f *vaultFailover) recover(ctx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.current == 0 || f.recovery == 0 || time.Since(f.lastCheck) < f.recovery {
+		return
+	}
+	f.lastCheck = time.Now()
+	for idx := 0; idx < f.current; idx++ {
+		if f.healthy(ctx, f.addresses[idx]) {
+			f.switchTo(idx)
+			return
+		}
+	}
+}
+
+func (f *vaultFailover) switchTo(idx int) {
+	if err := f.client.SetAddress(f.addresses[idx]); err != nil {
+		return
+	}
+	f.current = idx
+	f.lastCheck = time.Now()
+}
+
+// healthy reports whether Vault at address is initialized, unsealed and could serve reads, standby nodes are healthy as well
+func (f *vaultFailover) healthy(ctx context.Context, address string) bool {
+	ctx, cancel := context.WithTimeout(ctx, vaultHealthTimeout)
+	defer cancel()
+	url := strings.TrimSuffix(address, "/") + "/v1/sys/health?standbyok=true&perfstandbyok=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	drainBody(resp.Body)
+	return resp.StatusCode == http.StatusOK
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failoverTestServer is a Vault node which could be taken down, healthStatus is returned by sys/health when it is up
+type failoverTestServer struct {
+	mu           sync.Mutex
+	down         bool
+	healthStatus int
+	reads        int
+	writes       int
+}
+
+func (s *failoverTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	switch {
+	case r.URL.Path == "/v1/sys/health":
+		w.WriteHeader(s.healthStatus)
+	case r.Method == http.MethodGet:
+		s.reads++
+		writeVaultResponse(w, map[string]interface{}{"password": "pass"})
+	default:
+		s.writes++
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *failoverTestServer) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func TestVaultClient_Failover(t *testing.T) {
+	// prepare
+	primary := &failoverTestServer{healthStatus: http.StatusOK}
+	secondary := &failoverTestServer{healthStatus: http.StatusOK}
+	primarySrv := newVaultTestServer(t, primary.handler)
+	secondarySrv := newVaultTestServer(t, secondary.handler)
+	cli := newVaultTestClient(t, primarySrv, WithVaultAddresses(primarySrv.URL, secondarySrv.URL), WithVaultFailoverRecovery(0))
+	primary.setDown(true)
+
+	// make test
+	secret, err := cli.Read("secret/app")
+	primary.setDown(false)
+	_, secondErr := cli.Read("secret/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Nil(t, secondErr)
+	assert.Equal(t, "pass", secret.Data["password"])
+	assert.Equal(t, secondarySrv.URL, cli.Address())
+	assert.Equal(t, 0, primary.reads)
+	assert.Equal(t, 2, secondary.reads)
+}
+
+func TestVaultClient_Failover_Recovery(t *testing.T) {
+	// prepare
+	primary := &failoverTestServer{healthStatus: http.StatusOK}
+	secondary := &failoverTestServer{healthStatus: http.StatusOK}
+	primarySrv := newVaultTestServer(t, primary.handler)
+	secondarySrv := newVaultTestServer(t, secondary.handler)
+	cli := newVaultTestClient(t, primarySrv, WithVaultAddresses(primarySrv.URL, secondarySrv.URL), WithVaultFailoverRecovery(50*time.Millisecond))
+	primary.setDown(true)
+	_, err := cli.Read("secret/app")
+	assert.Nil(t, err)
+	primary.setDown(false)
+
+	// make test
+	_, stickyErr := cli.Read("secret/app")
+	time.Sleep(60 * time.Millisecond)
+	_, recoveredErr := cli.Read("secret/app")
+
+	// assertions
+	assert.Nil(t, stickyErr)
+	assert.Nil(t, recoveredErr)
+	assert.Equal(t, primarySrv.URL, cli.Address())
+	assert.Equal(t, 1, primary.reads)
+	assert.Equal(t, 2, secondary.reads)
+}
+
+func TestVaultClient_Failover_SkipsUnhealthy(t *testing.T) {
+	// prepare
+	primary := &failoverTestServer{healthStatus: http.StatusOK}
+	dr := &failoverTestServer{healthStatus: 472}
+	primarySrv := newVaultTestServer(t, primary.handler)
+	drSrv := newVaultTestServer(t, dr.handler)
+	cli := newVaultTestClient(t, primarySrv, WithVaultAddresses(primarySrv.URL, drSrv.URL))
+	primary.setDown(true)
+
+	// make test
+	_, err := cli.Read("secret/app")
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.Equal(t, primarySrv.URL, cli.Address())
+	assert.Equal(t, 0, dr.reads)
+}
+
+func TestVaultClient_Failover_WritesAreNotRetried(t *testing.T) {
+	// prepare
+	primary := &failoverTestServer{healthStatus: http.StatusOK}
+	secondary := &failoverTestServer{healthStatus: http.StatusOK}
+	primarySrv := newVaultTestServer(t, primary.handler)
+	secondarySrv := newVaultTestServer(t, secondary.handler)
+	cli := newVaultTestClient(t, primarySrv, WithVaultAddresses(primarySrv.URL, secondarySrv.URL))
+	primary.setDown(true)
+
+	// make test
+	_, err := cli.Write("secret/app", map[string]interface{}{"password": "pass"})
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, secondary.writes)
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const defaultJWTMountPath = "jwt"
+
+/*
+JWTAuth logs in to Vault using JWT/OIDC auth method
+JWT is taken from JWT field or read from JWTPath on each login, client logs in again when JWTPath file is rotated
+
+Example:
+cli, err := NewVaultClient(WithVaultAuth(&JWTAuth{Role: "ci", JWTPath: "/run/secrets/ci.jwt"}))
+*/
+type JWTAuth struct {
+	Role    string
+	JWT     string
+	JWTPath string
+	// MountPath of JWT auth method, default is "jwt"
+	MountPath string
+
+	version fileVersion
+}
+
+// Login logs in via auth/<mount>/login
+func (j *JWTAuth) Login(ctx context.Context, cli *api.Client) (*api.Secret, error) {
+	jwt := j.JWT
+	if j.JWTPath != "" {
+		fileJWT, err := readCredentialsFile(j.JWTPath, &j.version)
+		if err != nil {
+			return nil, fmt.Errorf("reading JWT: %w", err)
+		}
+		jwt = fileJWT
+	}
+	if jwt == "" {
+		return nil, errors.New("JWT is not set, use JWT or JWTPath")
+	}
+	return vaultLogin(ctx, cli, authLoginPath(j.MountPath, defaultJWTMountPath), map[string]interface{}{
+		"role": j.Role,
+		"jwt":  jwt,
+	})
+}
+
+// rotated reports if JWT file was changed since the last login
+func (j *JWTAuth) rotated() bool {
+	return j.JWTPath != "" && j.version.changed(j.JWTPath)
+}
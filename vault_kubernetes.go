@@ -0,0 +1,63 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultKubernetesMountPath = "kubernetes"
+	defaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+/*
+KubernetesAuth logs in to Vault using Kubernetes auth method with pod service account token
+Token file is read on each login, client logs in again when Vault token expires or when token file is rotated
+
+Example:
+cli, err := NewVaultClient(WithVaultKubernetes(&KubernetesAuth{Role: "my-service"}))
+*/
+type KubernetesAuth struct {
+	Role string
+	// MountPath of Kubernetes auth method, default is "kubernetes"
+	MountPath string
+	// TokenPath is path to service account token, default is /var/run/secrets/kubernetes.io/serviceaccount/token
+	TokenPath string
+
+	version fileVersion
+}
+
+// WithVaultKubernetes configures client to log in with Kubernetes service account token
+func WithVaultKubernetes(auth *KubernetesAuth) vaultClientOption {
+	return WithVaultAuth(auth)
+}
+
+// Login logs in via auth/<mount>/login
+func (k *KubernetesAuth) Login(ctx context.Context, cli *api.Client) (*api.Secret, error) {
+	if k.Role == "" {
+		return nil, errors.New("kubernetes auth role is not set")
+	}
+	jwt, err := readCredentialsFile(k.tokenPath(), &k.version)
+	if err != nil {
+		return nil, fmt.Errorf("reading service account token: %w", err)
+	}
+	return vaultLogin(ctx, cli, authLoginPath(k.MountPath, defaultKubernetesMountPath), map[string]interface{}{
+		"role": k.Role,
+		"jwt":  jwt,
+	})
+}
+
+// rotated reports if service account token file was changed since the last login
+func (k *KubernetesAuth) rotated() bool {
+	return k.version.changed(k.tokenPath())
+}
+
+func (k *KubernetesAuth) tokenPath() string {
+	if k.TokenPath == "" {
+		return defaultKubernetesTokenPath
+	}
+	return k.TokenPath
+}
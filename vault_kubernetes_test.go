@@ -0,0 +1,70 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type kubernetesTestServer struct {
+	jwts []string
+}
+
+func (s *kubernetesTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v1/auth/k8s/login" {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.jwts = append(s.jwts, body["role"]+":"+body["jwt"])
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": fmt.Sprintf("token-%d", len(s.jwts)), "lease_duration": 3600},
+		})
+		return
+	}
+	writeVaultResponse(w, map[string]interface{}{"token": r.Header.Get("X-Vault-Token")})
+}
+
+func TestVaultClient_Kubernetes_ReloginWhenTokenRotated(t *testing.T) {
+	// prepare
+	fake := &kubernetesTestServer{}
+	srv := newVaultTestServer(t, fake.handler)
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	_ = os.WriteFile(tokenPath, []byte("first-jwt"), 0600)
+	auth := &KubernetesAuth{Role: "app", MountPath: "k8s", TokenPath: tokenPath}
+	cli := newVaultTestClient(t, srv, WithVaultKubernetes(auth))
+
+	// make test
+	_, firstErr := cli.Read("secret/data/test")
+	_, cachedErr := cli.Read("secret/data/test")
+	_ = os.WriteFile(tokenPath, []byte("rotated-jwt"), 0600)
+	_ = os.Chtimes(tokenPath, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	secret, rotatedErr := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, cachedErr)
+	assert.Nil(t, rotatedErr)
+	assert.Equal(t, []string{"app:first-jwt", "app:rotated-jwt"}, fake.jwts)
+	assert.Equal(t, "token-2", secret.Data["token"])
+}
+
+func TestVaultClient_Kubernetes_Fails_TokenFileIsMissing(t *testing.T) {
+	// prepare
+	fake := &kubernetesTestServer{}
+	srv := newVaultTestServer(t, fake.handler)
+	auth := &KubernetesAuth{Role: "app", TokenPath: filepath.Join(t.TempDir(), "missing")}
+	cli := newVaultTestClient(t, srv, WithVaultKubernetes(auth))
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, secret)
+	assert.NotNil(t, err)
+	assert.Empty(t, fake.jwts)
+}
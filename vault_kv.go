@@ -0,0 +1,130 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// kvMount describes secrets engine mount which serves path, version is 0 if engine is not KV or could not be detected
+type kvMount struct {
+	path    string
+	version int
+}
+
+// kvMounts caches detected mounts by namespace and mount path
+type kvMounts struct {
+	mu     sync.RWMutex
+	mounts map[string]map[string]kvMount
+}
+
+// lookup returns the most specific cached mount serving path in namespace
+func (m *kvMounts) lookup(namespace, path string) (kvMount, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var found kvMount
+	for prefix, mount := range m.mounts[namespace] {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(found.path) {
+			found = mount
+		}
+	}
+	return found, found.path != ""
+}
+
+func (m *kvMounts) store(namespace string, mount kvMount) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.mounts == nil {
+		m.mounts = map[string]map[string]kvMount{}
+	}
+	if m.mounts[namespace] == nil {
+		m.mounts[namespace] = map[string]kvMount{}
+	}
+	m.mounts[namespace][mount.path] = mount
+}
+
+/*
+ReadData reads KV secret data stored under logical path, e.g. "secret/app"
+KV version is detected with sys/internal/ui/mounts, for KV v2 path is rewritten to "secret/data/app" and data is unwrapped,
+paths which already contain "data/" after mount path are accepted as well
+Returning error wrapping ErrVaultPathNotFound if there is no secret or it was deleted
+*/
+func (vc *VaultClient) ReadData(path string) (map[string]interface{}, error) {
+	return vc.ReadDataCtx(context.Background(), path)
+}
+
+// ReadDataCtx reads KV secret data like ReadData, ctx cancels the request
+func (vc *VaultClient) ReadDataCtx(ctx context.Context, path string) (map[string]interface{}, error) {
+	return vc.readData(ctx, path)
+}
+
+func (vc *VaultClient) readData(ctx context.Context, path string) (map[string]interface{}, error) {
+	secret, err := vc.readKV(ctx, path, 0)
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
+// detectKVMount returns mount serving path, if mount could not be detected (e.g. token has no access) zero mount is returned
+func (vc *VaultClient) detectKVMount(ctx context.Context, path string) kvMount {
+	path = strings.TrimPrefix(path, "/")
+	if mount, ok := vc.mounts.lookup(vc.namespace, path); ok {
+		return mount
+	}
+	secret, err := vc.cachedRead(ctx, "sys/internal/ui/mounts/"+path, nil, false)
+	if err != nil || secret == nil {
+		return kvMount{}
+	}
+	mountPath, _ := secret.Data["path"].(string)
+	if mountPath == "" {
+		return kvMount{}
+	}
+	mount := kvMount{path: mountPath}
+	if engine, _ := secret.Data["type"].(string); engine == "kv" || engine == "generic" {
+		mount.version = 1
+		if options, ok := secret.Data["options"].(map[string]interface{}); ok && fmt.Sprint(options["version"]) == "2" {
+			mount.version = 2
+		}
+	}
+	vc.mounts.store(vc.namespace, mount)
+	return mount
+}
+
+// dataPath rewrites logical path to KV v2 data path
+func (m kvMount) dataPath(path string) string {
+	return m.apiPath(path, "data/")
+}
+
+// metadataPath rewrites logical path to KV v2 metadata path
+func (m kvMount) metadataPath(path string) string {
+	return m.apiPath(path, "metadata/")
+}
+
+func (m kvMount) apiPath(path, prefix string) string {
+	path = strings.TrimPrefix(path, "/")
+	if m.version != 2 || !strings.HasPrefix(path, m.path) {
+		return path
+	}
+	rest := strings.TrimPrefix(path, m.path)
+	rest = strings.TrimPrefix(strings.TrimPrefix(rest, "data/"), "metadata/")
+	return m.path + prefix + rest
+}
+
+// unwrap returns secret data, for KV v2 it is stored under "data" key
+// if mount is unknown, "data" key is unwrapped only if it contains an object
+func (m kvMount) unwrap(data map[string]interface{}) map[string]interface{} {
+	switch m.version {
+	case 1:
+		return data
+	case 2:
+		inner, _ := data["data"].(map[string]interface{})
+		return inner
+	default:
+		if inner, ok := data["data"].(map[string]interface{}); ok {
+			return inner
+		}
+		return data
+	}
+}
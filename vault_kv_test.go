@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type kvTestServer struct {
+	mountLookups int
+	reads        []string
+}
+
+func (s *kvTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch {
+	case strings.HasPrefix(path, "sys/internal/ui/mounts/secret/"):
+		s.mountLookups++
+		writeVaultResponse(w, map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}})
+	case strings.HasPrefix(path, "sys/internal/ui/mounts/kv/"):
+		s.mountLookups++
+		writeVaultResponse(w, map[string]interface{}{"path": "kv/", "type": "kv", "options": map[string]interface{}{"version": "1"}})
+	case strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+	case path == "secret/data/app":
+		s.reads = append(s.reads, path)
+		writeVaultResponse(w, map[string]interface{}{"data": map[string]interface{}{"user": "v2"}, "metadata": map[string]interface{}{"version": 1}})
+	case path == "secret/data/deleted":
+		w.WriteHeader(http.StatusNotFound)
+		writeVaultResponse(w, map[string]interface{}{"data": nil, "metadata": map[string]interface{}{"version": 2}})
+	case path == "kv/app" || path == "legacy/data/app":
+		s.reads = append(s.reads, path)
+		writeVaultResponse(w, map[string]interface{}{"user": "v1"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestVaultClient_ReadData_KV2(t *testing.T) {
+	// prepare
+	fake := &kvTestServer{}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	logical, logicalErr := cli.ReadData("secret/app")
+	explicit, explicitErr := cli.ReadData("/secret/data/app")
+
+	// assertions
+	assert.Nil(t, logicalErr)
+	assert.Nil(t, explicitErr)
+	assert.Equal(t, map[string]interface{}{"user": "v2"}, logical)
+	assert.Equal(t, logical, explicit)
+	assert.Equal(t, []string{"secret/data/app", "secret/data/app"}, fake.reads)
+	assert.Equal(t, 1, fake.mountLookups)
+}
+
+func TestVaultClient_ReadData_KV1(t *testing.T) {
+	// prepare
+	fake := &kvTestServer{}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &vaultClientTestConfig{}
+
+	// make test
+	data, err := cli.ReadData("kv/app")
+	intoErr := cli.ReadInto("kv/app", cfg)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Nil(t, intoErr)
+	assert.Equal(t, map[string]interface{}{"user": "v1"}, data)
+	assert.Equal(t, "v1", cfg.User)
+}
+
+func TestVaultClient_ReadData_MountDetectionDenied(t *testing.T) {
+	// prepare
+	fake := &kvTestServer{}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	data, err := cli.ReadData("legacy/data/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"user": "v1"}, data)
+}
+
+func TestVaultClient_ReadData_Deleted(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&kvTestServer{}).handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	data, err := cli.ReadData("secret/deleted")
+
+	// assertions
+	assert.Nil(t, data)
+	assert.True(t, errors.Is(err, ErrVaultPathNotFound))
+}
+
+func TestKVMount_Paths(t *testing.T) {
+	// prepare
+	v2 := kvMount{path: "secret/", version: 2}
+	v1 := kvMount{path: "kv/", version: 1}
+
+	// assertions
+	assert.Equal(t, "secret/data/app/db", v2.dataPath("secret/app/db"))
+	assert.Equal(t, "secret/data/app", v2.dataPath("secret/data/app"))
+	assert.Equal(t, "secret/metadata/app", v2.metadataPath("secret/data/app"))
+	assert.Equal(t, "secret/metadata/app", v2.metadataPath("secret/app"))
+	assert.Equal(t, "kv/app", v1.dataPath("kv/app"))
+	assert.Equal(t, "kv/app", v1.metadataPath("kv/app"))
+}
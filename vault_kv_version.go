@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrVaultSecretRollback is returned by clients with rollback guard when Vault returns older version than was already seen
+var ErrVaultSecretRollback = errors.New("secret version is older than the last seen")
+
+// KVSecret is KV secret data with its version metadata, metadata fields are empty for KV v1
+type KVSecret struct {
+	Data           map[string]interface{}
+	Version        int
+	CreatedTime    time.Time
+	DeletionTime   time.Time
+	Destroyed      bool
+	CustomMetadata map[string]string
+}
+
+// KVMetadata is KV v2 secret metadata read from metadata/ endpoint
+type KVMetadata struct {
+	CurrentVersion int
+	OldestVersion  int
+	MaxVersions    int
+	CreatedTime    time.Time
+	UpdatedTime    time.Time
+	CustomMetadata map[string]string
+	Versions       map[int]KVVersionMetadata
+}
+
+// KVVersionMetadata describes single version of KV v2 secret
+type KVVersionMetadata struct {
+	CreatedTime  time.Time
+	DeletionTime time.Time
+	Destroyed    bool
+}
+
+// versionGuard remembers the latest seen version of each secret
+type versionGuard struct {
+	mu   sync.Mutex
+	seen map[string]int
+}
+
+// WithVaultRollbackGuard makes client refuse KV v2 versions older than the last version it has already read from the same path
+// Only reads of the latest version are checked, versions pinned with ReadKVVersion are returned as is
+func WithVaultRollbackGuard() vaultClientOption {
+	return func(s *vaultSettings) {
+		s.rollbackGuard = true
+	}
+}
+
+// ReadKV reads the latest version of KV secret, path syntax is the same as for ReadData
+func (vc *VaultClient) ReadKV(path string) (*KVSecret, error) {
+	return vc.ReadKVCtx(context.Background(), path)
+}
+
+// ReadKVCtx reads the latest version of KV secret like ReadKV, ctx cancels the request
+func (vc *VaultClient) ReadKVCtx(ctx context.Context, path string) (*KVSecret, error) {
+	return vc.readKV(ctx, path, 0)
+}
+
+// ReadKVVersion reads specific version of KV v2 secret, version 0 means the latest one
+func (vc *VaultClient) ReadKVVersion(path string, version int) (*KVSecret, error) {
+	return vc.ReadKVVersionCtx(context.Background(), path, version)
+}
+
+// ReadKVVersionCtx reads specific version of KV v2 secret like ReadKVVersion, ctx cancels the request
+func (vc *VaultClient) ReadKVVersionCtx(ctx context.Context, path string, version int) (*KVSecret, error) {
+	return vc.readKV(ctx, path, version)
+}
+
+// ReadMetadata reads KV v2 secret metadata, path syntax is the same as for ReadData
+func (vc *VaultClient) ReadMetadata(path string) (*KVMetadata, error) {
+	return vc.ReadMetadataCtx(context.Background(), path)
+}
+
+// ReadMetadataCtx reads KV v2 secret metadata like ReadMetadata, ctx cancels the request
+func (vc *VaultClient) ReadMetadataCtx(ctx context.Context, path string) (*KVMetadata, error) {
+	mount := vc.detectKVMount(ctx, path)
+	if mount.version != 2 {
+		return nil, fmt.Errorf("metadata is supported only by KV v2, path %s", path)
+	}
+	secret, err := vc.read(ctx, mount.metadataPath(path))
+	if err != nil {
+		return nil, err
+	}
+	raw := struct {
+		CurrentVersion int               `json:"current_version"`
+		OldestVersion  int               `json:"oldest_version"`
+		MaxVersions    int               `json:"max_versions"`
+		CreatedTime    string            `json:"created_time"`
+		UpdatedTime    string            `json:"updated_time"`
+		CustomMetadata map[string]string `json:"custom_metadata"`
+		Versions       map[string]struct {
+			CreatedTime  string `json:"created_time"`
+			DeletionTime string `json:"deletion_time"`
+			Destroyed    bool   `json:"destroyed"`
+		} `json:"versions"`
+	}{}
+	if err := remarshal(secret.Data, &raw); err != nil {
+		return nil, err
+	}
+	metadata := &KVMetadata{
+		CurrentVersion: raw.CurrentVersion,
+		OldestVersion:  raw.OldestVersion,
+		MaxVersions:    raw.MaxVersions,
+		CreatedTime:    parseVaultTime(raw.CreatedTime),
+		UpdatedTime:    parseVaultTime(raw.UpdatedTime),
+		CustomMetadata: raw.CustomMetadata,
+		Versions:       make(map[int]KVVersionMetadata, len(raw.Versions)),
+	}
+	for v, m := range raw.Versions {
+		version, convErr := strconv.Atoi(v)
+		if convErr != nil {
+			return nil, fmt.Errorf("invalid version %q in metadata of %s", v, path)
+		}
+		metadata.Versions[version] = KVVersionMetadata{
+			CreatedTime:  parseVaultTime(m.CreatedTime),
+			DeletionTime: parseVaultTime(m.DeletionTime),
+			Destroyed:    m.Destroyed,
+		}
+	}
+	return metadata, nil
+}
+
+func (vc *VaultClient) readKV(ctx context.Context, path string, version int) (*KVSecret, error) {
+	mount := vc.detectKVMount(ctx, path)
+	if version != 0 && mount.version != 2 {
+		return nil, fmt.Errorf("version pinning is supported only by KV v2, path %s", path)
+	}
+	var params url.Values
+	if version != 0 {
+		params = url.Values{"version": []string{strconv.Itoa(version)}}
+	}
+	dataPath := mount.dataPath(path)
+	secret, err := vc.cachedRead(ctx, dataPath, params, true)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, vaultPathNotFound(path)
+	}
+	data := mount.unwrap(secret.Data)
+	if data == nil {
+		return nil, vaultPathNotFound(path)
+	}
+	var metadata interface{}
+	// KV v1 secret could have own "metadata" key, version metadata is returned only by KV v2
+	if mount.version == 2 {
+		metadata = secret.Data["metadata"]
+	}
+	kv, err := newKVSecret(data, metadata)
+	if err != nil {
+		return nil, err
+	}
+	// pinned version is requested explicitly, only latest reads could be rolled back
+	if version == 0 {
+		if err := vc.checkRollback(dataPath, kv.Version); err != nil {
+			return nil, err
+		}
+	}
+	return kv, nil
+}
+
+func (vc *VaultClient) checkRollback(path string, version int) error {
+	if vc.guard == nil || version == 0 {
+		return nil
+	}
+	key := path
+	if vc.namespace != "" {
+		key = vc.namespace + "/" + path
+	}
+	vc.guard.mu.Lock()
+	defer vc.guard.mu.Unlock()
+	if seen := vc.guard.seen[key]; version < seen {
+		return fmt.Errorf("%w: %s version %d, seen %d", ErrVaultSecretRollback, path, version, seen)
+	}
+	vc.guard.seen[key] = version
+	return nil
+}
+
+// newKVSecret creates KVSecret from data and KV v2 version metadata, metadata is nil for KV v1
+func newKVSecret(data map[string]interface{}, versionMetadata interface{}) (*KVSecret, error) {
+	kv := &KVSecret{Data: data}
+	metadata, ok := versionMetadata.(map[string]interface{})
+	if !ok {
+		return kv, nil
+	}
+	raw := struct {
+		Version        int               `json:"version"`
+		CreatedTime    string            `json:"created_time"`
+		DeletionTime   string            `json:"deletion_time"`
+		Destroyed      bool              `json:"destroyed"`
+		CustomMetadata map[string]string `json:"custom_metadata"`
+	}{}
+	if err := remarshal(metadata, &raw); err != nil {
+		return nil, err
+	}
+	kv.Version = raw.Version
+	kv.CreatedTime = parseVaultTime(raw.CreatedTime)
+	kv.DeletionTime = parseVaultTime(raw.DeletionTime)
+	kv.Destroyed = raw.Destroyed
+	kv.CustomMetadata = raw.CustomMetadata
+	return kv, nil
+}
+
+// remarshal converts generic Vault response data to typed struct
+func remarshal(from interface{}, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// parseVaultTime parses RFC3339 time, empty or invalid value is returned as zero time
+func parseVaultTime(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, value)
+	return t
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vielendanke/go-config/configtest"
+)
+
+type kvVersionTestServer struct {
+	latest int
+}
+
+func (s *kvVersionTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch path {
+	case "sys/internal/ui/mounts/secret/app", "sys/internal/ui/mounts/secret/data/app":
+		writeVaultResponse(w, map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}})
+	case "sys/internal/ui/mounts/kv/app":
+		writeVaultResponse(w, map[string]interface{}{"path": "kv/", "type": "kv", "options": map[string]interface{}{"version": "1"}})
+	case "secret/data/app":
+		version := s.latest
+		if v := r.URL.Query().Get("version"); v != "" {
+			version, _ = strconv.Atoi(v)
+		}
+		writeVaultResponse(w, map[string]interface{}{
+			"data": map[string]interface{}{"password": "v" + strconv.Itoa(version)},
+			"metadata": map[string]interface{}{
+				"version":         version,
+				"created_time":    "2021-10-01T10:00:00.000000Z",
+				"deletion_time":   "",
+				"destroyed":       false,
+				"custom_metadata": map[string]interface{}{"owner": "team"},
+			},
+		})
+	case "secret/metadata/app":
+		writeVaultResponse(w, map[string]interface{}{
+			"current_version": s.latest,
+			"oldest_version":  1,
+			"max_versions":    0,
+			"created_time":    "2021-10-01T10:00:00Z",
+			"updated_time":    "2021-10-02T10:00:00Z",
+			"custom_metadata": map[string]interface{}{"owner": "team"},
+			"versions": map[string]interface{}{
+				"1": map[string]interface{}{"created_time": "2021-10-01T10:00:00Z", "deletion_time": "2021-10-03T10:00:00Z", "destroyed": true},
+				"2": map[string]interface{}{"created_time": "2021-10-02T10:00:00Z", "deletion_time": "", "destroyed": false},
+			},
+		})
+	case "kv/app":
+		writeVaultResponse(w, map[string]interface{}{"password": "v1"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestVaultClient_ReadKV(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&kvVersionTestServer{latest: 3}).handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.ReadKV("secret/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"password": "v3"}, secret.Data)
+	assert.Equal(t, 3, secret.Version)
+	assert.Equal(t, time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC), secret.CreatedTime)
+	assert.True(t, secret.DeletionTime.IsZero())
+	assert.Equal(t, map[string]string{"owner": "team"}, secret.CustomMetadata)
+}
+
+func TestVaultClient_ReadKVVersion(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&kvVersionTestServer{latest: 3}).handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.ReadKVVersion("secret/app", 2)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, 2, secret.Version)
+	assert.Equal(t, "v2", secret.Data["password"])
+}
+
+func TestVaultClient_ReadKVVersion_Fails_KV1(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&kvVersionTestServer{latest: 3}).handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.ReadKVVersion("kv/app", 2)
+
+	// assertions
+	assert.Nil(t, secret)
+	assert.NotNil(t, err)
+}
+
+func TestVaultClient_ReadMetadata(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&kvVersionTestServer{latest: 2}).handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	metadata, err := cli.ReadMetadata("secret/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, 2, metadata.CurrentVersion)
+	assert.Equal(t, 1, metadata.OldestVersion)
+	assert.Equal(t, map[string]string{"owner": "team"}, metadata.CustomMetadata)
+	assert.True(t, metadata.Versions[1].Destroyed)
+	assert.Equal(t, time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC), metadata.Versions[2].CreatedTime)
+}
+
+func TestVaultClient_RollbackGuard(t *testing.T) {
+	// prepare
+	fake := &kvVersionTestServer{latest: 3}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv, WithVaultRollbackGuard())
+	unguarded := newVaultTestClient(t, srv)
+
+	// make test
+	_, firstErr := cli.ReadKV("secret/app")
+	fake.latest = 2
+	secret, rollbackErr := cli.ReadKV("secret/data/app")
+	_, _ = unguarded.ReadKV("secret/app")
+	_, unguardedErr := unguarded.ReadKVVersion("secret/app", 1)
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secret)
+	assert.True(t, errors.Is(rollbackErr, ErrVaultSecretRollback))
+	assert.Nil(t, unguardedErr)
+}
+
+func TestVaultClient_RollbackGuard_PinnedVersion(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteSecret("secret/app", map[string]interface{}{"user": "old"})
+	vault.WriteSecret("secret/app", map[string]interface{}{"user": "new"})
+	cli := newFakeVaultClient(t, vault, WithVaultRollbackGuard())
+
+	// make test
+	latest, latestErr := cli.ReadKV("secret/app")
+	pinned, pinnedErr := cli.ReadKVVersion("secret/app", 1)
+	again, againErr := cli.ReadKV("secret/app")
+
+	// assertions
+	assert.Nil(t, latestErr)
+	assert.Nil(t, pinnedErr)
+	assert.Nil(t, againErr)
+	assert.Equal(t, 2, latest.Version)
+	assert.Equal(t, 1, pinned.Version)
+	assert.Equal(t, map[string]interface{}{"user": "old"}, pinned.Data)
+	assert.Equal(t, 2, again.Version)
+}
+
+func TestVaultClient_ReadKV_V1MetadataKeyIsData(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t, configtest.WithKVMount("kv", 1))
+	data := map[string]interface{}{"metadata": map[string]interface{}{"version": "abc"}, "user": "app"}
+	vault.WriteSecret("kv/app", data)
+	cli := newFakeVaultClient(t, vault, WithVaultRollbackGuard())
+
+	// make test
+	secret, err := cli.ReadKV("kv/app")
+	values, dataErr := cli.ReadData("kv/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Nil(t, dataErr)
+	assert.Equal(t, data, secret.Data)
+	assert.Equal(t, 0, secret.Version)
+	assert.Equal(t, data, values)
+}
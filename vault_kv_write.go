@@ -0,0 +1,201 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// ErrVaultCASMismatch is returned when check-and-set version does not match the current version of KV v2 secret
+var ErrVaultCASMismatch = errors.New("check-and-set version does not match the current version")
+
+const (
+	// mergePatchContentType is required by KV v2 for patch requests
+	mergePatchContentType = "application/merge-patch+json"
+	// noCAS disables check-and-set
+	noCAS = -1
+)
+
+type kvWriteSettings struct {
+	cas int
+}
+
+type kvWriteOption func(s *kvWriteSettings)
+
+// WithCAS makes write succeed only if the current version of KV v2 secret is version, 0 means secret should not exist
+func WithCAS(version int) kvWriteOption {
+	return func(s *kvWriteSettings) {
+		s.cas = version
+	}
+}
+
+/*
+PutKV replaces data of KV secret stored under logical path, path syntax is the same as for ReadData
+For KV v2 a new version is created and returned KVSecret contains its metadata, check-and-set is set with WithCAS
+Returning error wrapping ErrVaultCASMismatch if CAS version does not match
+
+Example:
+secret, err := cli.ReadKV("secret/app")
+_, err = cli.PutKV("secret/app", data, WithCAS(secret.Version))
+*/
+func (vc *VaultClient) PutKV(path string, data map[string]interface{}, opts ...kvWriteOption) (*KVSecret, error) {
+	return vc.PutKVCtx(context.Background(), path, data, opts...)
+}
+
+// PutKVCtx replaces data like PutKV, ctx cancels the request
+func (vc *VaultClient) PutKVCtx(ctx context.Context, path string, data map[string]interface{}, opts ...kvWriteOption) (*KVSecret, error) {
+	return vc.writeKV(ctx, http.MethodPut, path, data, opts...)
+}
+
+// PatchKV merges data into the latest version of KV v2 secret, keys with nil value are removed, secret should exist
+// Returning error wrapping ErrVaultPathNotFound if secret does not exist, other errors are the same as for PutKV
+func (vc *VaultClient) PatchKV(path string, data map[string]interface{}, opts ...kvWriteOption) (*KVSecret, error) {
+	return vc.PatchKVCtx(context.Background(), path, data, opts...)
+}
+
+// PatchKVCtx merges data like PatchKV, ctx cancels the request
+func (vc *VaultClient) PatchKVCtx(ctx context.Context, path string, data map[string]interface{}, opts ...kvWriteOption) (*KVSecret, error) {
+	return vc.writeKV(ctx, http.MethodPatch, path, data, opts...)
+}
+
+func (vc *VaultClient) writeKV(ctx context.Context, method, path string, data map[string]interface{}, opts ...kvWriteOption) (*KVSecret, error) {
+	s := &kvWriteSettings{cas: noCAS}
+	for _, opt := range opts {
+		opt(s)
+	}
+	mount := vc.detectKVMount(ctx, path)
+	if mount.version != 2 && (method == http.MethodPatch || s.cas != noCAS) {
+		return nil, fmt.Errorf("patch and check-and-set are supported only by KV v2, path %s", path)
+	}
+
+	var body map[string]interface{}
+	if mount.version == 2 {
+		body = map[string]interface{}{"data": data}
+		if s.cas != noCAS {
+			body["options"] = map[string]interface{}{"cas": s.cas}
+		}
+	} else {
+		body = data
+	}
+	secret, err := vc.request(ctx, method, mount.dataPath(path), body, nil)
+	if err != nil {
+		if isVaultCASMismatch(err) {
+			return nil, fmt.Errorf("%w: %s", ErrVaultCASMismatch, path)
+		}
+		return nil, err
+	}
+	if secret == nil {
+		if method == http.MethodPatch {
+			return nil, fmt.Errorf("%w: %s", ErrVaultPathNotFound, path)
+		}
+		// KV v1 responds with no content
+		return &KVSecret{Data: data}, nil
+	}
+	return newKVSecret(data, secret.Data)
+}
+
+func isVaultCASMismatch(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, e := range respErr.Errors {
+		if strings.Contains(e, "check-and-set") {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+SeedVault writes values of fields tagged with govault:"path#key" to Vault, tag syntax is the same as for ParseVault
+Fields with zero values are skipped, so values missing in local file do not clear secrets
+Values are merged with keys already stored under each path, for KV v2 they are written with check-and-set
+of the version they were merged with, so concurrent edits are not clobbered, ErrVaultCASMismatch is returned instead
+If the latest KV v2 version is deleted, values are written as a new version without merging
+
+Important note: cfg and all inner struct field should be initialized as pointers
+
+Example:
+cfg := &Config{}
+_ = ParseFile("bootstrap.yaml", YAML, cfg)
+err := SeedVault(cli, "secret/app", cfg)
+*/
+func SeedVault(vc *VaultClient, defaultPath string, cfg interface{}) error {
+	return SeedVaultCtx(context.Background(), vc, defaultPath, cfg)
+}
+
+// SeedVaultCtx writes values to Vault like SeedVault, ctx cancels requests to Vault
+func SeedVaultCtx(ctx context.Context, vc *VaultClient, defaultPath string, cfg interface{}) error {
+	if cfg == nil {
+		return nil
+	}
+	fields, err := collectVaultFields(reflect.ValueOf(cfg), defaultPath, "")
+	if err != nil {
+		return err
+	}
+	var paths []string
+	values := map[string]map[string]interface{}{}
+	for _, f := range fields {
+		if f.field.IsZero() {
+			continue
+		}
+		if values[f.path] == nil {
+			values[f.path] = map[string]interface{}{}
+			paths = append(paths, f.path)
+		}
+		values[f.path][f.key] = f.field.Interface()
+	}
+
+	for _, path := range paths {
+		if err := vc.seedPath(ctx, path, values[path]); err != nil {
+			return fmt.Errorf("path %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (vc *VaultClient) seedPath(ctx context.Context, path string, values map[string]interface{}) error {
+	data := map[string]interface{}{}
+	version := 0
+	kv2 := vc.detectKVMount(ctx, path).version == 2
+	if kv2 {
+		// current_version counts soft-deleted versions as well, CAS with the version of the latest readable data would never match
+		metadata, err := vc.ReadMetadataCtx(ctx, path)
+		switch {
+		case errors.Is(err, ErrVaultPathNotFound):
+		case err != nil:
+			return err
+		default:
+			version = metadata.CurrentVersion
+		}
+	}
+	if !kv2 || version > 0 {
+		// data of the version CAS is checked against is merged, so concurrent writes are not clobbered
+		current, err := vc.readKV(ctx, path, version)
+		switch {
+		case errors.Is(err, ErrVaultPathNotFound):
+		case err != nil:
+			return err
+		default:
+			for k, v := range current.Data {
+				data[k] = v
+			}
+		}
+	}
+	for k, v := range values {
+		data[k] = v
+	}
+
+	var opts []kvWriteOption
+	if kv2 {
+		opts = append(opts, WithCAS(version))
+	}
+	_, err := vc.writeKV(ctx, http.MethodPut, path, data, opts...)
+	return err
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vielendanke/go-config/configtest"
+)
+
+type seedTestConfig struct {
+	Name     string         `yaml:"name"`
+	Host     string         `yaml:"host" govault:"host"`
+	Port     int            `yaml:"port" govault:"port"`
+	Password string         `yaml:"password" govault:"secret/db#password"`
+	Empty    string         `yaml:"empty" govault:"empty"`
+	Inner    *seedTestInner `yaml:"inner"`
+}
+
+type seedTestInner struct {
+	APIKey string `yaml:"api_key" govault:"kv/api#key"`
+}
+
+func TestVaultClient_PutKV_CAS(t *testing.T) {
+	// prepare
+	cli := newFakeVaultClient(t, configtest.NewVault(t))
+
+	// make test
+	created, err := cli.PutKV("secret/app", map[string]interface{}{"user": "admin"}, WithCAS(0))
+	_, conflictErr := cli.PutKV("secret/app", map[string]interface{}{"user": "other"}, WithCAS(0))
+	updated, updateErr := cli.PutKV("secret/app", map[string]interface{}{"user": "root"}, WithCAS(created.Version))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, 1, created.Version)
+	assert.WithinDuration(t, time.Now(), created.CreatedTime, time.Minute)
+	assert.True(t, errors.Is(conflictErr, ErrVaultCASMismatch))
+	assert.Nil(t, updateErr)
+	assert.Equal(t, 2, updated.Version)
+}
+
+func TestVaultClient_PatchKV(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	cli := newFakeVaultClient(t, vault)
+	_, err := cli.PutKV("secret/app", map[string]interface{}{"user": "admin", "password": "pass"})
+	assert.Nil(t, err)
+
+	// make test
+	patched, err := cli.PatchKV("secret/app", map[string]interface{}{"password": nil, "host": "db"}, WithCAS(1))
+	_, missingErr := cli.PatchKV("secret/missing", map[string]interface{}{"host": "db"})
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, 2, patched.Version)
+	assert.Equal(t, map[string]interface{}{"user": "admin", "host": "db"}, vault.Secret("secret/app"))
+	assert.True(t, errors.Is(missingErr, ErrVaultPathNotFound))
+}
+
+func TestVaultClient_PatchKV_Fails_KV1(t *testing.T) {
+	// prepare
+	cli := newFakeVaultClient(t, configtest.NewVault(t, configtest.WithKVMount("kv", 1)))
+
+	// make test
+	_, err := cli.PatchKV("kv/app", map[string]interface{}{"host": "db"})
+
+	// assertions
+	assert.NotNil(t, err)
+}
+
+func TestSeedVault(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t, configtest.WithKVMount("kv", 1))
+	vault.WriteSecret("secret/app", map[string]interface{}{"host": "old"})
+	vault.WriteSecret("secret/app", map[string]interface{}{"user": "admin"})
+	vault.WriteSecret("secret/app", map[string]interface{}{"host": "old", "user": "admin"})
+	cli := newFakeVaultClient(t, vault)
+	data := []byte(`
+name: app
+host: db.local
+port: 5432
+password: pass
+inner:
+  api_key: key
+`)
+	cfg := &seedTestConfig{Inner: &seedTestInner{}}
+
+	// make test
+	err := NewConfig(cfg, WithParsingBytes(data, YAML), WithSeedingVault(cli, "secret/app"))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"host": "db.local", "port": float64(5432), "user": "admin"}, vault.Secret("secret/app"))
+	assert.Equal(t, map[string]interface{}{"password": "pass"}, vault.Secret("secret/db"))
+	assert.Equal(t, map[string]interface{}{"key": "key"}, vault.Secret("kv/api"))
+	secret, readErr := cli.ReadKV("secret/app")
+	assert.Nil(t, readErr)
+	assert.Equal(t, 4, secret.Version)
+}
+
+func TestSeedVault_LatestVersionDeleted(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteSecret("secret/app", map[string]interface{}{"host": "old", "user": "admin"})
+	vault.DeleteSecret("secret/app")
+	cli := newFakeVaultClient(t, vault)
+	cfg := &seedTestConfig{Host: "db.local", Inner: &seedTestInner{}}
+
+	// make test
+	err := SeedVault(cli, "secret/app", cfg)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"host": "db.local"}, vault.Secret("secret/app"))
+	secret, readErr := cli.ReadKV("secret/app")
+	assert.Nil(t, readErr)
+	assert.Equal(t, 2, secret.Version)
+}
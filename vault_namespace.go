@@ -0,0 +1,32 @@
+package config
+
+const (
+	envVaultNamespace    = "VAULT_NAMESPACE"
+	vaultNamespaceHeader = "X-Vault-Namespace"
+)
+
+// WithVaultNamespace sets Vault Enterprise namespace used for all requests including login,
+// by default it is fetching from VAULT_NAMESPACE Environment variable
+func WithVaultNamespace(namespace string) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.namespace = namespace
+	}
+}
+
+/*
+WithNamespace returns client sending requests to namespace, e.g. "team-a" or "team-a/app"
+Returned client shares connections, token, auth method and caches with vc, so it is cheap to create it per request
+
+Example:
+data, err := cli.WithNamespace("team-a").ReadData("secret/app")
+*/
+func (vc *VaultClient) WithNamespace(namespace string) *VaultClient {
+	c := *vc
+	c.namespace = namespace
+	return &c
+}
+
+// Namespace returns namespace used for requests, empty string means root namespace
+func (vc *VaultClient) Namespace() string {
+	return vc.namespace
+}
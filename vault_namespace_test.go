@@ -0,0 +1,108 @@
+package config
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// namespaceTestServer records namespace header of each request by path
+type namespaceTestServer struct {
+	mu         sync.Mutex
+	namespaces map[string][]string
+}
+
+func (s *namespaceTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.namespaces[r.URL.Path] = append(s.namespaces[r.URL.Path], r.Header.Get(vaultNamespaceHeader))
+	s.mu.Unlock()
+	if r.URL.Path == "/v1/sys/internal/ui/mounts/secret/app" {
+		writeVaultResponse(w, map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "1"}})
+		return
+	}
+	writeVaultResponse(w, map[string]interface{}{"user": "admin"})
+}
+
+func TestVaultClient_Namespace_FromEnv(t *testing.T) {
+	// prepare
+	t.Setenv(envVaultNamespace, "team-a")
+	fake := &namespaceTestServer{namespaces: map[string][]string{}}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	_, err := cli.Read("secret/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "team-a", cli.Namespace())
+	assert.Equal(t, []string{"team-a"}, fake.namespaces["/v1/secret/app"])
+}
+
+func TestVaultClient_WithNamespace(t *testing.T) {
+	// prepare
+	fake := &namespaceTestServer{namespaces: map[string][]string{}}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv, WithVaultNamespace("team-a"))
+
+	// make test
+	_, err := cli.WithNamespace("team-a/app").ReadData("secret/app")
+	_, rootErr := cli.WithNamespace("").ReadData("secret/app")
+	_, againErr := cli.WithNamespace("team-a/app").ReadData("secret/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Nil(t, rootErr)
+	assert.Nil(t, againErr)
+	assert.Equal(t, []string{"team-a/app", "", "team-a/app"}, fake.namespaces["/v1/secret/app"])
+	assert.Equal(t, []string{"team-a/app", ""}, fake.namespaces["/v1/sys/internal/ui/mounts/secret/app"])
+	assert.Equal(t, "team-a", cli.Namespace())
+}
+
+func TestVaultClient_WithNamespace_Unwrap(t *testing.T) {
+	// prepare
+	wrapping := newWrappingTestServer()
+	fake := &namespaceTestServer{namespaces: map[string][]string{}}
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		fake.namespaces[r.URL.Path] = append(fake.namespaces[r.URL.Path], r.Header.Get(vaultNamespaceHeader))
+		fake.mu.Unlock()
+		wrapping.handler(w, r)
+	})
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.WithNamespace("team-a").Unwrap("wrapped-secret", "secret/data/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.NotNil(t, secret)
+	assert.Equal(t, []string{"team-a"}, fake.namespaces["/v1/sys/wrapping/lookup"])
+	assert.Equal(t, []string{"team-a"}, fake.namespaces["/v1/sys/wrapping/unwrap"])
+}
+
+func TestVaultClient_WithNamespace_LoginInClientNamespace(t *testing.T) {
+	// prepare
+	appRole := &appRoleTestServer{leaseTTL: 3600}
+	fake := &namespaceTestServer{namespaces: map[string][]string{}}
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		fake.namespaces[r.URL.Path] = append(fake.namespaces[r.URL.Path], r.Header.Get(vaultNamespaceHeader))
+		fake.mu.Unlock()
+		appRole.handler(w, r)
+	})
+	auth := &AppRoleAuth{RoleID: "role", SecretID: "wrapping", WrappedSecretID: true}
+	cli := newVaultTestClient(t, srv, WithVaultNamespace("team-a"), WithVaultAppRole(auth))
+
+	// make test
+	_, err := cli.WithNamespace("team-a/app").Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team-a"}, fake.namespaces["/v1/sys/wrapping/lookup"])
+	assert.Equal(t, []string{"team-a"}, fake.namespaces["/v1/sys/wrapping/unwrap"])
+	assert.Equal(t, []string{"team-a"}, fake.namespaces["/v1/auth/approle/login"])
+	assert.Equal(t, []string{"team-a/app"}, fake.namespaces["/v1/secret/data/test"])
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	vaultTag = "govault"
+)
+
+// vaultField is a struct field which value is stored in Vault
+type vaultField struct {
+	field reflect.Value
+	name  string
+	path  string
+	key   string
+}
+
+/*
+ParseVault method takes config interface as argument
+runs over the fields of incoming interface, if field contains tag govault:"path#key"
+after it is reading secret stored under path and injecting value of key into field, if key is not found - do nothing
+Tag without path (govault:"key" or govault:"#key") reads key from defaultPath
+Each path is read once and paths are read concurrently, KV v1 and v2 paths are supported in the same way as in VaultClient.ReadData
+Values are converted with the same rules as in ParseEnv, so "5432" could be injected into int field
+
+Important note: cfg and all inner struct field should be initialized as pointers
+
+Example:
+type DB struct {
+   Host     string `govault:"host"`
+   Port     int    `govault:"port"`
+   Password string `govault:"secret/data/db#password"`
+}
+
+err := ParseVault(cli, "secret/data/app", &DB{})
+*/
+func ParseVault(vc *VaultClient, defaultPath string, cfg interface{}) error {
+	return ParseVaultCtx(context.Background(), vc, defaultPath, cfg)
+}
+
+// ParseVaultCtx parses fields tagged with govault like ParseVault, ctx cancels requests to Vault
+func ParseVaultCtx(ctx context.Context, vc *VaultClient, defaultPath string, cfg interface{}) error {
+	if cfg == nil {
+		return nil
+	}
+	fields, err := collectVaultFields(reflect.ValueOf(cfg), defaultPath, "")
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(fields))
+	for _, f := range fields {
+		paths = append(paths, f.path)
+	}
+	batch := vc.ReadBatchCtx(ctx, paths, 0)
+	for _, f := range fields {
+		result := batch.Results[f.path]
+		if result.Err != nil {
+			return fmt.Errorf("field %s: %w", f.name, result.Err)
+		}
+		value, found := result.Data[f.key]
+		if !found || value == nil {
+			continue
+		}
+		str, convErr := vaultValueString(value)
+		if convErr != nil {
+			return fmt.Errorf("field %s: %w", f.name, convErr)
+		}
+		if setErr := setFieldString(f.field, str); setErr != nil {
+			return fmt.Errorf("field %s: %w", f.name, setErr)
+		}
+	}
+	return nil
+}
+
+func collectVaultFields(v reflect.Value, defaultPath, prefix string) ([]vaultField, error) {
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, nil
+	}
+	el := v.Elem()
+	t := el.Type()
+	var fields []vaultField
+	for i := 0; i < el.NumField(); i++ {
+		name := prefix + t.Field(i).Name
+		if el.Field(i).Kind() == reflect.Ptr {
+			inner, err := collectVaultFields(el.Field(i), defaultPath, name+".")
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, inner...)
+			continue
+		}
+		tag := t.Field(i).Tag.Get(vaultTag)
+		if tag == "" {
+			continue
+		}
+		path, key := splitVaultTag(tag, defaultPath)
+		if path == "" || key == "" {
+			return nil, fmt.Errorf("field %s: govault tag %q should contain path and key or default path should be set", name, tag)
+		}
+		fields = append(fields, vaultField{field: el.Field(i), name: name, path: path, key: key})
+	}
+	return fields, nil
+}
+
+// splitVaultTag splits "path#key" tag, tag without "#" is a key stored under defaultPath
+func splitVaultTag(tag, defaultPath string) (path, key string) {
+	idx := strings.LastIndex(tag, "#")
+	if idx == -1 {
+		return defaultPath, tag
+	}
+	path, key = tag[:idx], tag[idx+1:]
+	if path == "" {
+		path = defaultPath
+	}
+	return path, key
+}
+
+// vaultValueString converts scalar Vault value to string, nested objects and lists are not supported
+func vaultValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number, bool, float64, int:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type vaultParseTestConfig struct {
+	Name     string               `json:"name"`
+	Host     string               `json:"host" govault:"host"`
+	Port     int                  `json:"port" govault:"#port"`
+	Debug    bool                 `govault:"debug"`
+	Missing  string               `json:"missing" govault:"missing"`
+	Password string               `govault:"secret/data/db#password"`
+	Inner    *vaultParseTestInner `json:"inner"`
+}
+
+type vaultParseTestInner struct {
+	APIKey string `govault:"secret/data/api#key"`
+}
+
+type vaultParseTestServer struct {
+	mu    sync.Mutex
+	reads map[string]int
+}
+
+func (s *vaultParseTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if strings.HasPrefix(path, "sys/internal/ui/mounts/") {
+		writeVaultResponse(w, map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}})
+		return
+	}
+	s.mu.Lock()
+	s.reads[path]++
+	s.mu.Unlock()
+	switch path {
+	case "secret/data/app":
+		writeVaultResponse(w, map[string]interface{}{"data": map[string]interface{}{"host": "db.local", "port": "5432", "debug": true}})
+	case "secret/data/db":
+		writeVaultResponse(w, map[string]interface{}{"data": map[string]interface{}{"password": "pass", "user": "admin"}})
+	case "secret/data/api":
+		writeVaultResponse(w, map[string]interface{}{"data": map[string]interface{}{"key": 42}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestParseVault(t *testing.T) {
+	// prepare
+	fake := &vaultParseTestServer{reads: map[string]int{}}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &vaultParseTestConfig{Missing: "default", Inner: &vaultParseTestInner{}}
+
+	// make test
+	err := ParseVault(cli, "secret/app", cfg)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "db.local", cfg.Host)
+	assert.Equal(t, 5432, cfg.Port)
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, "default", cfg.Missing)
+	assert.Equal(t, "pass", cfg.Password)
+	assert.Equal(t, "42", cfg.Inner.APIKey)
+	assert.Equal(t, map[string]int{"secret/data/app": 1, "secret/data/db": 1, "secret/data/api": 1}, fake.reads)
+}
+
+func TestNewConfig_WithParsingVaultClient_MergesFields(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&vaultParseTestServer{reads: map[string]int{}}).handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &vaultParseTestConfig{Inner: &vaultParseTestInner{}}
+	data := []byte(`{"name":"app","host":"localhost","missing":"from-file"}`)
+
+	// make test
+	err := NewConfig(cfg, WithParsingBytes(data, JSON), WithParsingVaultClient(cli, "secret/app"))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "app", cfg.Name)
+	assert.Equal(t, "db.local", cfg.Host)
+	assert.Equal(t, "from-file", cfg.Missing)
+	assert.Equal(t, "pass", cfg.Password)
+}
+
+func TestParseVault_Fails_DefaultPathIsEmpty(t *testing.T) {
+	// prepare
+	fake := &vaultParseTestServer{reads: map[string]int{}}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	err := ParseVault(cli, "", &vaultParseTestConfig{Inner: &vaultParseTestInner{}})
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Host")
+	assert.Empty(t, fake.reads)
+}
+
+func TestParseVault_Fails_PathNotFound(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&vaultParseTestServer{reads: map[string]int{}}).handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	err := ParseVault(cli, "secret/missing", &vaultParseTestConfig{Inner: &vaultParseTestInner{}})
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "field Host")
+}
@@ -0,0 +1,437 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPKIMount          = "pki"
+	defaultCertRenewFraction = 2.0 / 3.0
+	certEventsBuffer         = 16
+)
+
+// CertEventType describes what happened with certificate
+type CertEventType int
+
+const (
+	// CertIssued is sent when the first certificate was issued
+	CertIssued CertEventType = iota
+	// CertRotated is sent when certificate was replaced with a new one
+	CertRotated
+	// CertIssueFailed is sent when certificate could not be issued, issuer retries it later
+	CertIssueFailed
+)
+
+func (t CertEventType) String() string {
+	switch t {
+	case CertIssued:
+		return "certificate issued"
+	case CertRotated:
+		return "certificate rotated"
+	case CertIssueFailed:
+		return "certificate issue failed"
+	default:
+		return "unknown"
+	}
+}
+
+// CertEvent is sent by VaultCertIssuer on every issue attempt
+type CertEvent struct {
+	Type CertEventType
+	// SerialNumber and NotAfter describe issued certificate
+	SerialNumber string
+	NotAfter     time.Time
+	Err          error
+}
+
+/*
+VaultCertIssuer issues certificates from Vault PKI pki/issue/<role> and renews them
+after a fraction of their lifetime has passed (2/3 by default)
+GetCertificate and GetClientCertificate always return the current certificate, so tls.Config using them picks up rotations
+Events are sent to the channel returned by Events and to the callback set with WithCertCallback,
+the channel is closed when Run returns, so Run should be called only once
+
+Example:
+issuer := NewVaultCertIssuer(cli, "service", "app.service.internal", WithCertTTL(24*time.Hour))
+if err := issuer.Issue(ctx); err != nil { ... }
+go issuer.Run(ctx)
+srv := &http.Server{TLSConfig: issuer.ServerTLSConfig(tls.RequireAndVerifyClientCert)}
+*/
+type VaultCertIssuer struct {
+	vc            *VaultClient
+	mount         string
+	role          string
+	commonName    string
+	altNames      []string
+	ipSANs        []string
+	ttl           time.Duration
+	renewFraction float64
+	retryInterval time.Duration
+	events        chan CertEvent
+	callback      func(CertEvent)
+	// eventsMu guards events against sending after they were closed by Run, Issue could be called later
+	eventsMu     sync.Mutex
+	eventsClosed bool
+
+	issueMu sync.Mutex
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	caPool  *x509.CertPool
+	due     time.Time
+}
+
+type certIssuerOption func(i *VaultCertIssuer)
+
+// WithCertMount sets mount path of PKI secrets engine, default is "pki"
+func WithCertMount(mount string) certIssuerOption {
+	return func(i *VaultCertIssuer) {
+		i.mount = mount
+	}
+}
+
+// WithCertAltNames sets DNS and email subject alternative names
+func WithCertAltNames(names ...string) certIssuerOption {
+	return func(i *VaultCertIssuer) {
+		i.altNames = names
+	}
+}
+
+// WithCertIPSANs sets IP subject alternative names
+func WithCertIPSANs(ips ...string) certIssuerOption {
+	return func(i *VaultCertIssuer) {
+		i.ipSANs = ips
+	}
+}
+
+// WithCertTTL sets requested certificate TTL, role TTL is used by default
+func WithCertTTL(ttl time.Duration) certIssuerOption {
+	return func(i *VaultCertIssuer) {
+		i.ttl = ttl
+	}
+}
+
+// WithCertRenewFraction sets part of certificate lifetime after which it is renewed, should be between 0 and 1
+func WithCertRenewFraction(fraction float64) certIssuerOption {
+	return func(i *VaultCertIssuer) {
+		i.renewFraction = fraction
+	}
+}
+
+// WithCertRetryInterval sets wait before retry of failed issue, default is 5 seconds
+func WithCertRetryInterval(interval time.Duration) certIssuerOption {
+	return func(i *VaultCertIssuer) {
+		i.retryInterval = interval
+	}
+}
+
+// WithCertCallback sets function called synchronously for every event
+func WithCertCallback(callback func(CertEvent)) certIssuerOption {
+	return func(i *VaultCertIssuer) {
+		i.callback = callback
+	}
+}
+
+// NewVaultCertIssuer creates issuer of certificates for role and commonName, certificate is issued by Issue or Run
+func NewVaultCertIssuer(vc *VaultClient, role, commonName string, opts ...certIssuerOption) *VaultCertIssuer {
+	i := &VaultCertIssuer{
+		vc:            vc,
+		mount:         defaultPKIMount,
+		role:          role,
+		commonName:    commonName,
+		renewFraction: defaultCertRenewFraction,
+		retryInterval: defaultWatcherRetryInterval,
+		events:        make(chan CertEvent, certEventsBuffer),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Events returns channel with events, events are dropped if channel buffer is full, channel is closed when Run returns
+func (i *VaultCertIssuer) Events() <-chan CertEvent {
+	return i.events
+}
+
+// Issue issues a new certificate and makes it current
+func (i *VaultCertIssuer) Issue(ctx context.Context) error {
+	i.issueMu.Lock()
+	defer i.issueMu.Unlock()
+	return i.issue(ctx)
+}
+
+// Run renews certificate until ctx is done, certificate is issued first if it was not issued yet
+func (i *VaultCertIssuer) Run(ctx context.Context) error {
+	defer i.closeEvents()
+	if i.current() == nil {
+		_ = i.Issue(ctx)
+	}
+	for {
+		timer := time.NewTimer(time.Until(i.nextDue()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			_ = i.Issue(ctx)
+		}
+	}
+}
+
+// Certificate returns the current certificate, it is issued on the first call if Issue or Run were not called
+func (i *VaultCertIssuer) Certificate() (*tls.Certificate, error) {
+	return i.certificate(context.Background())
+}
+
+// GetCertificate could be used as tls.Config GetCertificate callback, the first certificate is issued with handshake context
+func (i *VaultCertIssuer) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	ctx := context.Background()
+	if hello != nil {
+		ctx = hello.Context()
+	}
+	return i.certificate(ctx)
+}
+
+// GetClientCertificate could be used as tls.Config GetClientCertificate callback, the first certificate is issued with handshake context
+func (i *VaultCertIssuer) GetClientCertificate(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	ctx := context.Background()
+	if info != nil {
+		ctx = info.Context()
+	}
+	return i.certificate(ctx)
+}
+
+// CAPool returns pool with issuing CA and CA chain of the current certificate, nil if certificate was not issued yet
+func (i *VaultCertIssuer) CAPool() *x509.CertPool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.caPool
+}
+
+/*
+ServerTLSConfig returns tls.Config serving the current certificate, client certificates are requested according to clientAuth
+and verified with CAPool taken on every handshake, so CA rotation is picked up, tls.NoClientCert disables mutual TLS
+
+Example:
+srv := &http.Server{TLSConfig: issuer.ServerTLSConfig(tls.RequireAndVerifyClientCert)}
+*/
+func (i *VaultCertIssuer) ServerTLSConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: i.GetCertificate, ClientAuth: clientAuth}
+	// ClientCAs is a snapshot, so certificates are requested without verification and verified with the current pool
+	switch clientAuth {
+	case tls.VerifyClientCertIfGiven:
+		cfg.ClientAuth = tls.RequestClientCert
+	case tls.RequireAndVerifyClientCert:
+		cfg.ClientAuth = tls.RequireAnyClientCert
+	default:
+		return cfg
+	}
+	cfg.VerifyPeerCertificate = i.verifyClientCertificate
+	return cfg
+}
+
+// verifyClientCertificate verifies client certificate chain with the current CAPool
+func (i *VaultCertIssuer) verifyClientCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return nil
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("invalid client certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	return i.verifyChain(certs, x509.VerifyOptions{KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+}
+
+/*
+ClientTLSConfig returns tls.Config presenting the current certificate and verifying server certificates
+with CAPool taken on every handshake, so CA rotation is picked up
+Handshake fails if certificate was not issued yet, server name is verified as usual
+
+Example:
+client := &http.Client{Transport: &http.Transport{TLSClientConfig: issuer.ClientTLSConfig()}}
+*/
+func (i *VaultCertIssuer) ClientTLSConfig() *tls.Config {
+	// RootCAs is a snapshot, so default verification is skipped and server is verified with the current pool
+	return &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		GetClientCertificate: i.GetClientCertificate,
+		InsecureSkipVerify:   true,
+		VerifyConnection:     i.verifyServerConnection,
+	}
+}
+
+// verifyServerConnection verifies server certificate chain and name with the current CAPool
+func (i *VaultCertIssuer) verifyServerConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server did not present certificate")
+	}
+	return i.verifyChain(cs.PeerCertificates, x509.VerifyOptions{DNSName: cs.ServerName})
+}
+
+// verifyChain verifies leaf certs[0] with the current CAPool, the rest of certs are intermediates
+func (i *VaultCertIssuer) verifyChain(certs []*x509.Certificate, opts x509.VerifyOptions) error {
+	pool := i.CAPool()
+	if pool == nil {
+		return errors.New("peer certificate could not be verified, certificate was not issued yet")
+	}
+	opts.Roots = pool
+	opts.Intermediates = x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
+// certificate returns the current certificate and issues it with ctx if it was not issued yet
+func (i *VaultCertIssuer) certificate(ctx context.Context) (*tls.Certificate, error) {
+	if cert := i.current(); cert != nil {
+		return cert, nil
+	}
+	i.issueMu.Lock()
+	defer i.issueMu.Unlock()
+	if cert := i.current(); cert != nil {
+		return cert, nil
+	}
+	if err := i.issue(ctx); err != nil {
+		return nil, err
+	}
+	return i.current(), nil
+}
+
+func (i *VaultCertIssuer) current() *tls.Certificate {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.cert
+}
+
+func (i *VaultCertIssuer) nextDue() time.Time {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.due
+}
+
+func (i *VaultCertIssuer) issue(ctx context.Context) error {
+	cert, pool, err := i.request(ctx)
+	if err != nil {
+		i.mu.Lock()
+		i.due = time.Now().Add(i.retryInterval)
+		i.mu.Unlock()
+		i.emit(CertEvent{Type: CertIssueFailed, Err: err})
+		return err
+	}
+	leaf := cert.Leaf
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+
+	i.mu.Lock()
+	eventType := CertRotated
+	if i.cert == nil {
+		eventType = CertIssued
+	}
+	i.cert = cert
+	i.caPool = pool
+	i.due = leaf.NotBefore.Add(time.Duration(float64(lifetime) * i.renewFraction))
+	if i.due.Before(time.Now()) {
+		// certificate lifetime is too short, do not reissue it in a loop
+		i.due = time.Now().Add(i.retryInterval)
+	}
+	i.mu.Unlock()
+
+	i.emit(CertEvent{Type: eventType, SerialNumber: formatSerial(leaf), NotAfter: leaf.NotAfter})
+	return nil
+}
+
+// request issues certificate, returned certificate has parsed Leaf
+func (i *VaultCertIssuer) request(ctx context.Context) (*tls.Certificate, *x509.CertPool, error) {
+	body := map[string]interface{}{"common_name": i.commonName}
+	if len(i.altNames) > 0 {
+		body["alt_names"] = strings.Join(i.altNames, ",")
+	}
+	if len(i.ipSANs) > 0 {
+		body["ip_sans"] = strings.Join(i.ipSANs, ",")
+	}
+	if i.ttl > 0 {
+		body["ttl"] = i.ttl.String()
+	}
+	path := i.mount + "/issue/" + i.role
+	secret, err := i.vc.request(ctx, http.MethodPut, path, body, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secret == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrVaultPathNotFound, path)
+	}
+	certPEM, _ := secret.Data["certificate"].(string)
+	keyPEM, _ := secret.Data["private_key"].(string)
+	if certPEM == "" || keyPEM == "" {
+		return nil, nil, errors.New("pki response does not contain certificate or private key")
+	}
+
+	pool := x509.NewCertPool()
+	chain := certPEM
+	if ca, ok := secret.Data["issuing_ca"].(string); ok && ca != "" {
+		pool.AppendCertsFromPEM([]byte(ca))
+	}
+	if caChain, ok := secret.Data["ca_chain"].([]interface{}); ok {
+		for _, c := range caChain {
+			if ca, ok := c.(string); ok {
+				pool.AppendCertsFromPEM([]byte(ca))
+				chain += "\n" + ca
+			}
+		}
+	}
+	cert, err := tls.X509KeyPair([]byte(chain), []byte(keyPEM))
+	if err != nil {
+		return nil, nil, fmt.Errorf("pki response contains invalid key pair: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	cert.Leaf = leaf
+	return &cert, pool, nil
+}
+
+func (i *VaultCertIssuer) emit(e CertEvent) {
+	if i.callback != nil {
+		i.callback(e)
+	}
+	i.eventsMu.Lock()
+	defer i.eventsMu.Unlock()
+	if i.eventsClosed {
+		return
+	}
+	select {
+	case i.events <- e:
+	default:
+	}
+}
+
+func (i *VaultCertIssuer) closeEvents() {
+	i.eventsMu.Lock()
+	defer i.eventsMu.Unlock()
+	i.eventsClosed = true
+	close(i.events)
+}
+
+// formatSerial formats serial number in the same way as Vault does, e.g. "39:dd:2e"
+func formatSerial(cert *x509.Certificate) string {
+	b := cert.SerialNumber.Bytes()
+	parts := make([]string, len(b))
+	for idx, v := range b {
+		parts[idx] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pkiTestServer issues certificates signed by its own CA, valid for lifetime
+type pkiTestServer struct {
+	t        *testing.T
+	ca       *x509.Certificate
+	caKey    *ecdsa.PrivateKey
+	caPEM    string
+	lifetime time.Duration
+
+	mu       sync.Mutex
+	issued   int
+	requests []map[string]interface{}
+}
+
+func newPKITestServer(t *testing.T, lifetime time.Duration) *pkiTestServer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "go-config CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+	return &pkiTestServer{
+		t:        t,
+		ca:       ca,
+		caKey:    key,
+		caPEM:    string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		lifetime: lifetime,
+	}
+}
+
+func (s *pkiTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/pki/issue/service" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	s.mu.Lock()
+	s.issued++
+	serial := s.issued + 1
+	s.requests = append(s.requests, body)
+	s.mu.Unlock()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(serial)),
+		Subject:      pkix.Name{CommonName: body["common_name"].(string)},
+		DNSNames:     []string{body["common_name"].(string)},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(s.lifetime),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, s.ca, &key.PublicKey, s.caKey)
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	writeVaultResponse(w, map[string]interface{}{
+		"certificate":   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		"private_key":   string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})),
+		"issuing_ca":    s.caPEM,
+		"ca_chain":      []string{s.caPEM},
+		"serial_number": "",
+	})
+}
+
+func TestVaultCertIssuer_Issue(t *testing.T) {
+	// prepare
+	fake := newPKITestServer(t, time.Hour)
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+	issuer := NewVaultCertIssuer(cli, "service", "app.internal",
+		WithCertAltNames("app", "app.local"), WithCertIPSANs("127.0.0.1"), WithCertTTL(time.Hour))
+
+	// make test
+	err := issuer.Issue(context.Background())
+
+	// assertions
+	assert.Nil(t, err)
+	cert, certErr := issuer.GetCertificate(nil)
+	assert.Nil(t, certErr)
+	assert.Equal(t, "app.internal", cert.Leaf.Subject.CommonName)
+	assert.Len(t, cert.Certificate, 2)
+	assert.Equal(t, map[string]interface{}{
+		"common_name": "app.internal",
+		"alt_names":   "app,app.local",
+		"ip_sans":     "127.0.0.1",
+		"ttl":         "1h0m0s",
+	}, fake.requests[0])
+	e := <-issuer.Events()
+	assert.Equal(t, CertIssued, e.Type)
+	assert.Equal(t, "02", e.SerialNumber)
+}
+
+func TestVaultCertIssuer_Run_RotatesCertificate(t *testing.T) {
+	// prepare
+	// certificate NotBefore is truncated to seconds, so lifetime should be longer than a second
+	fake := newPKITestServer(t, 2*time.Second)
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+	issuer := NewVaultCertIssuer(cli, "service", "app.internal", WithCertRenewFraction(0.5), WithCertRetryInterval(50*time.Millisecond))
+	first, err := issuer.Certificate()
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// make test
+	go func() {
+		_ = issuer.Run(ctx)
+	}()
+
+	// assertions
+	assert.Equal(t, CertIssued, (<-issuer.Events()).Type)
+	select {
+	case e := <-issuer.Events():
+		assert.Equal(t, CertRotated, e.Type)
+	case <-ctx.Done():
+		t.Fatal("certificate was not rotated")
+	}
+	second, err := issuer.GetClientCertificate(nil)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.Leaf.SerialNumber, second.Leaf.SerialNumber)
+}
+
+func TestVaultCertIssuer_Run_ClosesEvents(t *testing.T) {
+	// prepare
+	issuer := NewVaultCertIssuer(newVaultTestClient(t, newVaultTestServer(t, newPKITestServer(t, time.Hour).handler)), "service", "app.internal")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+
+	// make test
+	go func() {
+		done <- issuer.Run(ctx)
+	}()
+	var events []CertEventType
+	for e := range issuer.Events() {
+		events = append(events, e.Type)
+		cancel()
+	}
+	issueErr := issuer.Issue(context.Background())
+
+	// assertions
+	assert.Equal(t, []CertEventType{CertIssued}, events)
+	assert.True(t, errors.Is(<-done, context.Canceled))
+	assert.Nil(t, issueErr)
+}
+
+// serveTLSTestServer serves common name of client certificate, or "anonymous" if client has no certificate
+func serveTLSTestServer(t *testing.T, cfg *tls.Config) string {
+	// httptest server can not be used here, its own certificate takes precedence over GetCertificate
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			_, _ = w.Write([]byte("anonymous"))
+			return
+		}
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	})}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+	return "https://" + ln.Addr().String()
+}
+
+func getTLSTestServer(cfg *tls.Config, url string) (string, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestVaultCertIssuer_MutualTLS(t *testing.T) {
+	// prepare
+	fake := newPKITestServer(t, time.Hour)
+	vaultSrv := newVaultTestServer(t, fake.handler)
+	issuer := NewVaultCertIssuer(newVaultTestClient(t, vaultSrv), "service", "app.internal")
+	assert.Nil(t, issuer.Issue(context.Background()))
+	url := serveTLSTestServer(t, issuer.ServerTLSConfig(tls.RequireAndVerifyClientCert))
+
+	// make test
+	body, err := getTLSTestServer(issuer.ClientTLSConfig(), url)
+	_, anonymousErr := getTLSTestServer(&tls.Config{RootCAs: issuer.CAPool()}, url)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "app.internal", body)
+	assert.NotNil(t, anonymousErr)
+}
+
+func TestVaultCertIssuer_MutualTLS_Fails_UnknownCA(t *testing.T) {
+	// prepare
+	issuer := NewVaultCertIssuer(newVaultTestClient(t, newVaultTestServer(t, newPKITestServer(t, time.Hour).handler)), "service", "app.internal")
+	other := NewVaultCertIssuer(newVaultTestClient(t, newVaultTestServer(t, newPKITestServer(t, time.Hour).handler)), "service", "other.internal")
+	assert.Nil(t, issuer.Issue(context.Background()))
+	assert.Nil(t, other.Issue(context.Background()))
+	url := serveTLSTestServer(t, issuer.ServerTLSConfig(tls.RequireAndVerifyClientCert))
+	clientCfg := other.ClientTLSConfig()
+	clientCfg.RootCAs = issuer.CAPool()
+
+	// make test
+	_, err := getTLSTestServer(clientCfg, url)
+
+	// assertions
+	assert.NotNil(t, err)
+}
+
+func TestVaultCertIssuer_MutualTLS_CARotation(t *testing.T) {
+	// prepare
+	var mu sync.Mutex
+	current := newPKITestServer(t, time.Hour)
+	vaultSrv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fake := current
+		mu.Unlock()
+		fake.handler(w, r)
+	})
+	issuer := NewVaultCertIssuer(newVaultTestClient(t, vaultSrv), "service", "app.internal")
+	client := NewVaultCertIssuer(newVaultTestClient(t, vaultSrv), "service", "client.internal")
+	assert.Nil(t, issuer.Issue(context.Background()))
+	url := serveTLSTestServer(t, issuer.ServerTLSConfig(tls.RequireAndVerifyClientCert))
+
+	// make test
+	mu.Lock()
+	current = newPKITestServer(t, time.Hour)
+	mu.Unlock()
+	assert.Nil(t, issuer.Issue(context.Background()))
+	assert.Nil(t, client.Issue(context.Background()))
+	body, err := getTLSTestServer(client.ClientTLSConfig(), url)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "client.internal", body)
+}
+
+func TestVaultCertIssuer_ClientTLSConfig_CARotation(t *testing.T) {
+	// prepare
+	var mu sync.Mutex
+	current := newPKITestServer(t, time.Hour)
+	vaultSrv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fake := current
+		mu.Unlock()
+		fake.handler(w, r)
+	})
+	issuer := NewVaultCertIssuer(newVaultTestClient(t, vaultSrv), "service", "app.internal")
+	client := NewVaultCertIssuer(newVaultTestClient(t, vaultSrv), "service", "client.internal")
+	assert.Nil(t, issuer.Issue(context.Background()))
+	assert.Nil(t, client.Issue(context.Background()))
+	// config is created once, before CA rotation
+	clientCfg := client.ClientTLSConfig()
+	url := serveTLSTestServer(t, issuer.ServerTLSConfig(tls.RequireAndVerifyClientCert))
+
+	// make test
+	mu.Lock()
+	current = newPKITestServer(t, time.Hour)
+	mu.Unlock()
+	assert.Nil(t, issuer.Issue(context.Background()))
+	assert.Nil(t, client.Issue(context.Background()))
+	body, err := getTLSTestServer(clientCfg, url)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "client.internal", body)
+}
+
+func TestVaultCertIssuer_ClientTLSConfig_Fails_NotIssued(t *testing.T) {
+	// prepare
+	issuer := NewVaultCertIssuer(newVaultTestClient(t, newVaultTestServer(t, newPKITestServer(t, time.Hour).handler)), "service", "app.internal")
+	client := NewVaultCertIssuer(newVaultTestClient(t, newVaultTestServer(t, newPKITestServer(t, time.Hour).handler)), "service", "client.internal")
+	assert.Nil(t, issuer.Issue(context.Background()))
+	url := serveTLSTestServer(t, issuer.ServerTLSConfig(tls.NoClientCert))
+
+	// make test
+	_, err := getTLSTestServer(client.ClientTLSConfig(), url)
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.Nil(t, client.CAPool())
+}
+
+func TestVaultCertIssuer_ServerTLSConfig_NoClientCert(t *testing.T) {
+	// prepare
+	issuer := NewVaultCertIssuer(newVaultTestClient(t, newVaultTestServer(t, newPKITestServer(t, time.Hour).handler)), "service", "app.internal")
+	cfg := issuer.ServerTLSConfig(tls.NoClientCert)
+	url := serveTLSTestServer(t, cfg)
+
+	// make test
+	// the first certificate is issued during handshake
+	body, err := getTLSTestServer(&tls.Config{InsecureSkipVerify: true}, url)
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "anonymous", body)
+	assert.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+	assert.Nil(t, cfg.VerifyPeerCertificate)
+}
+
+func TestVaultCertIssuer_Fails_RoleNotFound(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, newPKITestServer(t, time.Hour).handler)
+	issuer := NewVaultCertIssuer(newVaultTestClient(t, srv), "missing", "app.internal")
+
+	// make test
+	_, err := issuer.Certificate()
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.Equal(t, CertIssueFailed, (<-issuer.Events()).Type)
+	assert.Equal(t, tls.VersionTLS12, int(issuer.ClientTLSConfig().MinVersion))
+}
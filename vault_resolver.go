@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+/*
+VaultResolver resolves vault://path#key and ${vault:path#key} references
+All keys of the same path are resolved with a single read, paths are read concurrently, KV v1 and v2 paths are supported as in VaultClient.ReadData
+If Client is nil, it is created from Environment on the first use
+*/
+type VaultResolver struct {
+	Client *VaultClient
+
+	once    sync.Once
+	initErr error
+}
+
+// Scheme returns "vault"
+func (r *VaultResolver) Scheme() string {
+	return "vault"
+}
+
+// Resolve reads secrets grouped by path
+func (r *VaultResolver) Resolve(ctx context.Context, refs []string) (map[string]string, error) {
+	r.once.Do(func() {
+		if r.Client == nil {
+			r.Client, r.initErr = NewVaultClient()
+		}
+	})
+	if r.initErr != nil {
+		return nil, r.initErr
+	}
+
+	keys := map[string][]string{}
+	for _, ref := range refs {
+		path, key := splitVaultTag(ref, "")
+		if path == "" || key == "" {
+			return nil, &ReferenceError{Ref: "vault://" + ref, Err: errors.New("reference should be in path#key form")}
+		}
+		keys[path] = append(keys[path], key)
+	}
+
+	paths := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		path, _ := splitVaultTag(ref, "")
+		paths = append(paths, path)
+	}
+	batch := r.Client.ReadBatchCtx(ctx, paths, 0)
+
+	values := make(map[string]string, len(refs))
+	for _, path := range batch.Paths {
+		pathKeys := keys[path]
+		result := batch.Results[path]
+		if result.Err != nil {
+			return nil, &ReferenceError{Ref: "vault://" + path + "#" + pathKeys[0], Err: result.Err}
+		}
+		for _, key := range pathKeys {
+			ref := path + "#" + key
+			value, ok := result.Data[key]
+			if !ok || value == nil {
+				return nil, &ReferenceError{Ref: "vault://" + ref, Err: fmt.Errorf("key %s not found", key)}
+			}
+			str, convErr := vaultValueString(value)
+			if convErr != nil {
+				return nil, &ReferenceError{Ref: "vault://" + ref, Err: convErr}
+			}
+			values[ref] = str
+		}
+	}
+	return values, nil
+}
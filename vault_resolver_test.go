@@ -0,0 +1,46 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultResolver_ReadsPathOnce(t *testing.T) {
+	// prepare
+	fake := &vaultParseTestServer{reads: map[string]int{}}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &resolverTestConfig{
+		Password: "vault://secret/db#password",
+		URL:      "postgres://${vault:secret/db#user}:${vault:secret/db#password}@db",
+		Key:      "vault://secret/api#key",
+	}
+
+	// make test
+	err := ResolveReferences(cfg, &VaultResolver{Client: cli})
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "pass", cfg.Password)
+	assert.Equal(t, "postgres://admin:pass@db", cfg.URL)
+	assert.Equal(t, "42", cfg.Key)
+	assert.Equal(t, map[string]int{"secret/data/db": 1, "secret/data/api": 1}, fake.reads)
+}
+
+func TestVaultResolver_Fails_KeyNotFound(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&vaultParseTestServer{reads: map[string]int{}}).handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &resolverTestConfig{Inner: &resolverTestInner{Token: "vault://secret/db#token"}}
+
+	// make test
+	err := ResolveReferences(cfg, &VaultResolver{Client: cli})
+
+	// assertions
+	var refErr *ReferenceError
+	assert.True(t, errors.As(err, &refErr))
+	assert.Equal(t, "vault://secret/db#token", refErr.Ref)
+	assert.Equal(t, "Inner.Token", refErr.Path)
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	envVaultCACert        = "VAULT_CACERT"
+	envVaultCAPath        = "VAULT_CAPATH"
+	envVaultClientCert    = "VAULT_CLIENT_CERT"
+	envVaultClientKey     = "VAULT_CLIENT_KEY"
+	envVaultTLSServerName = "VAULT_TLS_SERVER_NAME"
+	envVaultSkipVerify    = "VAULT_SKIP_VERIFY"
+)
+
+type vaultTLSSettings struct {
+	caCert     string
+	caPath     string
+	clientCert string
+	clientKey  string
+	serverName string
+	insecure   bool
+}
+
+// WithVaultCACert sets path to PEM encoded CA certificate used to verify Vault server, default is VAULT_CACERT Environment variable
+// Important note: TLS options are ignored if WithVaultHTTPClient is used
+func WithVaultCACert(path string) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.tls.caCert = path
+	}
+}
+
+// WithVaultCAPath sets path to directory with PEM encoded CA certificates, default is VAULT_CAPATH Environment variable
+func WithVaultCAPath(path string) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.tls.caPath = path
+	}
+}
+
+// WithVaultClientCert sets paths to PEM encoded client certificate and key, default is VAULT_CLIENT_CERT and VAULT_CLIENT_KEY Environment variables
+func WithVaultClientCert(certPath, keyPath string) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.tls.clientCert = certPath
+		s.tls.clientKey = keyPath
+	}
+}
+
+// WithVaultTLSServerName sets name used to verify Vault server certificate, default is VAULT_TLS_SERVER_NAME Environment variable
+func WithVaultTLSServerName(name string) vaultClientOption {
+	return func(s *vaultSettings) {
+		s.tls.serverName = name
+	}
+}
+
+// WithVaultInsecureSkipVerify disables verification of Vault server certificate, it should never be used in production
+// The same could be done with VAULT_SKIP_VERIFY=true Environment variable
+func WithVaultInsecureSkipVerify() vaultClientOption {
+	return func(s *vaultSettings) {
+		s.tls.insecure = true
+	}
+}
+
+func vaultTLSFromEnv() (vaultTLSSettings, error) {
+	s := vaultTLSSettings{
+		caCert:     os.Getenv(envVaultCACert),
+		caPath:     os.Getenv(envVaultCAPath),
+		clientCert: os.Getenv(envVaultClientCert),
+		clientKey:  os.Getenv(envVaultClientKey),
+		serverName: os.Getenv(envVaultTLSServerName),
+	}
+	if skip := os.Getenv(envVaultSkipVerify); skip != "" {
+		insecure, err := strconv.ParseBool(skip)
+		if err != nil {
+			return s, fmt.Errorf("invalid %s value: %w", envVaultSkipVerify, err)
+		}
+		s.insecure = insecure
+	}
+	return s, nil
+}
+
+/*
+buildTLSConfig creates tls.Config verifying Vault server certificate
+System certificate pool is used if neither CA certificate nor CA path is set
+*/
+func buildTLSConfig(s vaultTLSSettings) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         s.serverName,
+		InsecureSkipVerify: s.insecure,
+	}
+	if s.caCert != "" || s.caPath != "" {
+		pool := x509.NewCertPool()
+		if s.caCert != "" {
+			if err := appendCertFile(pool, s.caCert); err != nil {
+				return nil, err
+			}
+		}
+		if s.caPath != "" {
+			if err := appendCertDir(pool, s.caPath); err != nil {
+				return nil, err
+			}
+		}
+		cfg.RootCAs = pool
+	}
+	if s.clientCert != "" || s.clientKey != "" {
+		if s.clientCert == "" || s.clientKey == "" {
+			return nil, errors.New("both client certificate and client key should be set")
+		}
+		cert, err := tls.LoadX509KeyPair(s.clientCert, s.clientKey)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func appendCertFile(pool *x509.CertPool, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return nil
+}
+
+func appendCertDir(pool *x509.CertPool, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, rErr := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if rErr != nil {
+			return rErr
+		}
+		if pool.AppendCertsFromPEM(data) {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no PEM certificates found in %s", dir)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newVaultTLSTestServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeVaultResponse(w, map[string]interface{}{"data": map[string]interface{}{"user": "admin"}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeServerCA(t *testing.T, srv *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writeTestKeyPair(t *testing.T, dir string) (certPath, keyPath string, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "go-config"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	_ = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	cert, _ = x509.ParseCertificate(der)
+	return certPath, keyPath, cert
+}
+
+func TestVaultClient_TLS_VerifiesByDefault(t *testing.T) {
+	// prepare
+	srv := newVaultTLSTestServer(t)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, secret)
+	assert.NotNil(t, err)
+}
+
+func TestVaultClient_TLS_CACert(t *testing.T) {
+	// prepare
+	srv := newVaultTLSTestServer(t)
+	cli := newVaultTestClient(t, srv, WithVaultCACert(writeServerCA(t, srv)))
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.NotNil(t, secret)
+}
+
+func TestVaultClient_TLS_CACertEnv(t *testing.T) {
+	// prepare
+	srv := newVaultTLSTestServer(t)
+	t.Setenv(envVaultCACert, writeServerCA(t, srv))
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.NotNil(t, secret)
+}
+
+func TestVaultClient_TLS_CAPath(t *testing.T) {
+	// prepare
+	srv := newVaultTLSTestServer(t)
+	cli := newVaultTestClient(t, srv, WithVaultCAPath(filepath.Dir(writeServerCA(t, srv))))
+
+	// make test
+	secret, err := cli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.NotNil(t, secret)
+}
+
+func TestVaultClient_TLS_ServerName(t *testing.T) {
+	// prepare
+	srv := newVaultTLSTestServer(t)
+	ca := writeServerCA(t, srv)
+	valid := newVaultTestClient(t, srv, WithVaultCACert(ca), WithVaultTLSServerName("example.com"))
+	invalid := newVaultTestClient(t, srv, WithVaultCACert(ca), WithVaultTLSServerName("vault.internal"))
+
+	// make test
+	_, validErr := valid.Read("secret/data/test")
+	_, invalidErr := invalid.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, validErr)
+	assert.NotNil(t, invalidErr)
+}
+
+func TestVaultClient_TLS_Insecure(t *testing.T) {
+	// prepare
+	srv := newVaultTLSTestServer(t)
+	t.Setenv(envVaultSkipVerify, "true")
+	envCli := newVaultTestClient(t, srv)
+	t.Setenv(envVaultSkipVerify, "")
+	optCli := newVaultTestClient(t, srv, WithVaultInsecureSkipVerify())
+
+	// make test
+	_, envErr := envCli.Read("secret/data/test")
+	_, optErr := optCli.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, envErr)
+	assert.Nil(t, optErr)
+}
+
+func TestVaultClient_TLS_ClientCert(t *testing.T) {
+	// prepare
+	certPath, keyPath, clientCert := writeTestKeyPair(t, t.TempDir())
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeVaultResponse(w, map[string]interface{}{"common_name": r.TLS.PeerCertificates[0].Subject.CommonName})
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	ca := writeServerCA(t, srv)
+	withCert := newVaultTestClient(t, srv, WithVaultCACert(ca), WithVaultClientCert(certPath, keyPath))
+	withoutCert := newVaultTestClient(t, srv, WithVaultCACert(ca))
+
+	// make test
+	secret, withCertErr := withCert.Read("secret/data/test")
+	_, withoutCertErr := withoutCert.Read("secret/data/test")
+
+	// assertions
+	assert.Nil(t, withCertErr)
+	assert.Equal(t, "go-config", secret.Data["common_name"])
+	assert.NotNil(t, withoutCertErr)
+}
+
+func TestNewVaultClient_Fails_ClientKeyIsEmpty(t *testing.T) {
+	// prepare
+	certPath, _, _ := writeTestKeyPair(t, t.TempDir())
+
+	// make test
+	cli, err := NewVaultClient(WithVaultAddress("https://127.0.0.1:8200"), WithVaultClientCert(certPath, ""))
+
+	// assertions
+	assert.Nil(t, cli)
+	assert.NotNil(t, err)
+}
+
+func TestNewVaultClient_Fails_CACertIsInvalid(t *testing.T) {
+	// prepare
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	_ = os.WriteFile(path, []byte("not a certificate"), 0600)
+
+	// make test
+	cli, err := NewVaultClient(WithVaultAddress("https://127.0.0.1:8200"), WithVaultCACert(path))
+
+	// assertions
+	assert.Nil(t, cli)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	envVaultToken     = "VAULT_TOKEN"
+	envVaultTokenSink = "VAULT_TOKEN_SINK"
+	vaultTokenFile    = ".vault-token"
+)
+
+// ErrVaultTokenNotFound is returned when none of token sources in the chain has a token
+var ErrVaultTokenNotFound = errors.New("vault token is not found")
+
+/*
+TokenSource provides Vault token from a single place, e.g. environment variable or file
+Name describes the source in errors, empty token with nil error means the source has no token
+Built-in sources are TokenFromValue, TokenFromEnv, TokenFromFile and TokenFromHomeFile
+*/
+type TokenSource interface {
+	Name() string
+	Token() (string, error)
+}
+
+type valueTokenSource string
+
+// TokenFromValue returns source of explicitly passed token
+func TokenFromValue(token string) TokenSource {
+	return valueTokenSource(token)
+}
+
+func (v valueTokenSource) Name() string {
+	return "explicit token"
+}
+
+func (v valueTokenSource) Token() (string, error) {
+	return strings.TrimSpace(string(v)), nil
+}
+
+type envTokenSource string
+
+// TokenFromEnv returns source reading token from Environment variable key
+func TokenFromEnv(key string) TokenSource {
+	return envTokenSource(key)
+}
+
+func (e envTokenSource) Name() string {
+	return "environment variable " + string(e)
+}
+
+func (e envTokenSource) Token() (string, error) {
+	return strings.TrimSpace(os.Getenv(string(e))), nil
+}
+
+// fileTokenSource reads token from file, e.g. Vault Agent sink, file is read again after it was changed
+type fileTokenSource struct {
+	name    string
+	path    func() (string, error)
+	version fileVersion
+}
+
+// TokenFromFile returns source reading token from file, e.g. Vault Agent sink which is rewritten on each renewal
+// Client logs in with the new token as soon as file is changed
+func TokenFromFile(path string) TokenSource {
+	return &fileTokenSource{
+		name: "token file " + path,
+		path: func() (string, error) { return path, nil },
+	}
+}
+
+// TokenFromHomeFile returns source reading ~/.vault-token written by "vault login"
+func TokenFromHomeFile() TokenSource {
+	return &fileTokenSource{
+		name: "~/" + vaultTokenFile,
+		path: func() (string, error) {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(home, vaultTokenFile), nil
+		},
+	}
+}
+
+func (f *fileTokenSource) Name() string {
+	return f.name
+}
+
+func (f *fileTokenSource) Token() (string, error) {
+	path, err := f.path()
+	if err != nil {
+		return "", err
+	}
+	return readCredentialsFile(path, &f.version)
+}
+
+func (f *fileTokenSource) rotated() bool {
+	path, err := f.path()
+	return err == nil && f.version.changed(path)
+}
+
+/*
+DefaultTokenSources returns default token chain: explicit token, VAULT_TOKEN Environment variable,
+file set in VAULT_TOKEN_SINK Environment variable (e.g. Vault Agent sink) and ~/.vault-token
+Empty token and not set sink are skipped
+*/
+func DefaultTokenSources(token string) []TokenSource {
+	var sources []TokenSource
+	if token != "" {
+		sources = append(sources, TokenFromValue(token))
+	}
+	sources = append(sources, TokenFromEnv(envVaultToken))
+	if sink := os.Getenv(envVaultTokenSink); sink != "" {
+		sources = append(sources, TokenFromFile(sink))
+	}
+	return append(sources, TokenFromHomeFile())
+}
+
+/*
+TokenChainAuth uses token from the first source in Sources which has it
+Sources are checked again each time client logs in, that is when Vault rejects the token or token file is changed
+Returning error wrapping ErrVaultTokenNotFound which lists all tried sources if none of them has a token
+
+Example:
+cli, err := NewVaultClient(WithVaultTokenChain(TokenFromEnv("VAULT_TOKEN"), TokenFromFile("/run/vault-agent/token")))
+*/
+type TokenChainAuth struct {
+	Sources []TokenSource
+}
+
+// WithVaultTokenChain configures client to log in with TokenChainAuth, DefaultTokenSources are used if sources are not passed
+func WithVaultTokenChain(sources ...TokenSource) vaultClientOption {
+	if len(sources) == 0 {
+		sources = DefaultTokenSources("")
+	}
+	return WithVaultAuth(&TokenChainAuth{Sources: sources})
+}
+
+// Login returns token of the first source which has it
+func (a *TokenChainAuth) Login(ctx context.Context, cli *api.Client) (*api.Secret, error) {
+	tried := make([]string, 0, len(a.Sources))
+	for _, source := range a.Sources {
+		token, err := source.Token()
+		switch {
+		case err != nil:
+			tried = append(tried, fmt.Sprintf("%s (%v)", source.Name(), err))
+		case token == "":
+			tried = append(tried, source.Name()+" (empty)")
+		default:
+			return &api.Secret{Auth: &api.SecretAuth{ClientToken: token}}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w, tried: %s", ErrVaultTokenNotFound, strings.Join(tried, ", "))
+}
+
+func (a *TokenChainAuth) rotated() bool {
+	for _, source := range a.Sources {
+		if r, ok := source.(rotatingAuthMethod); ok && r.rotated() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tokenTestServer(t *testing.T, opts ...vaultClientOption) (*VaultClient, func() []string) {
+	var mu sync.Mutex
+	var tokens []string
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokens = append(tokens, r.Header.Get("X-Vault-Token"))
+		mu.Unlock()
+		writeVaultResponse(w, map[string]interface{}{"key": "value"})
+	})
+	cli := newVaultTestClient(t, srv, opts...)
+	return cli, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), tokens...)
+	}
+}
+
+func TestTokenChainAuth_SinkFile(t *testing.T) {
+	// prepare
+	t.Setenv(envVaultToken, "")
+	sink := filepath.Join(t.TempDir(), "sink")
+	assert.Nil(t, os.WriteFile(sink, []byte("agent-token\n"), 0600))
+	cli, tokens := tokenTestServer(t, WithVaultTokenChain(TokenFromEnv(envVaultToken), TokenFromFile(sink)))
+
+	// make test
+	_, firstErr := cli.Read("secret/app")
+	assert.Nil(t, os.WriteFile(sink, []byte("renewed-agent-token\n"), 0600))
+	_, secondErr := cli.Read("secret/app")
+
+	// assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secondErr)
+	assert.Equal(t, []string{"agent-token", "renewed-agent-token"}, tokens())
+}
+
+func TestTokenChainAuth_Order(t *testing.T) {
+	// prepare
+	t.Setenv(envVaultToken, "env-token")
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	assert.Nil(t, os.WriteFile(filepath.Join(home, vaultTokenFile), []byte("home-token"), 0600))
+	auth := &TokenChainAuth{Sources: DefaultTokenSources("explicit-token")}
+
+	// make test
+	explicit, explicitErr := auth.Login(context.Background(), nil)
+	auth.Sources = DefaultTokenSources("")
+	env, envErr := auth.Login(context.Background(), nil)
+	t.Setenv(envVaultToken, "")
+	homeSecret, homeErr := auth.Login(context.Background(), nil)
+
+	// assertions
+	assert.Nil(t, explicitErr)
+	assert.Nil(t, envErr)
+	assert.Nil(t, homeErr)
+	assert.Equal(t, "explicit-token", explicit.Auth.ClientToken)
+	assert.Equal(t, "env-token", env.Auth.ClientToken)
+	assert.Equal(t, "home-token", homeSecret.Auth.ClientToken)
+}
+
+func TestTokenChainAuth_Fails_NoToken(t *testing.T) {
+	// prepare
+	t.Setenv(envVaultToken, "")
+	t.Setenv(envVaultTokenSink, filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("HOME", t.TempDir())
+	srv := newVaultTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeVaultResponse(w, map[string]interface{}{})
+	})
+	cli := newVaultTestClient(t, srv, WithVaultTokenChain())
+
+	// make test
+	_, err := cli.Read("secret/app")
+
+	// assertions
+	assert.True(t, errors.Is(err, ErrVaultTokenNotFound))
+	assert.True(t, strings.Contains(err.Error(), "environment variable VAULT_TOKEN (empty)"))
+	assert.True(t, strings.Contains(err.Error(), "token file "))
+	assert.True(t, strings.Contains(err.Error(), "~/.vault-token"))
+}
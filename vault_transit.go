@@ -0,0 +1,180 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+)
+
+const (
+	transitTag = "gotransit"
+
+	defaultTransitMount = "transit"
+	transitBatchSize    = 100
+)
+
+// transitCiphertextPattern matches ciphertext produced by Vault Transit, e.g. vault:v1:...
+var transitCiphertextPattern = regexp.MustCompile(`^vault:v[0-9]+:`)
+
+type transitSettings struct {
+	mount string
+	key   string
+}
+
+type transitOption func(s *transitSettings)
+
+// WithTransitMount sets mount path of Transit secrets engine, default is "transit"
+func WithTransitMount(mount string) transitOption {
+	return func(s *transitSettings) {
+		s.mount = mount
+	}
+}
+
+// WithTransitKey sets key used for ciphertext found in fields without gotransit tag
+func WithTransitKey(key string) transitOption {
+	return func(s *transitSettings) {
+		s.key = key
+	}
+}
+
+/*
+DecryptTransit runs over string values of cfg (struct fields, slices, maps and interface values)
+and replaces Vault Transit ciphertext (values starting with vault:v<N>:) with plaintext
+Key is taken from gotransit:"key" tag of the field, values of untagged fields are decrypted with key set by WithTransitKey
+Ciphertext is decrypted in batches, one request per key for up to 100 values
+Plaintext is never logged, Reloader redacts all decrypted fields whether they are tagged with gotransit or not
+
+Example:
+type DB struct {
+   Password string `yaml:"password" gotransit:"app"`
+}
+
+err := DecryptTransit(cli, cfg, WithTransitMount("transit"))
+*/
+func DecryptTransit(vc *VaultClient, cfg interface{}, opts ...transitOption) error {
+	return DecryptTransitCtx(context.Background(), vc, cfg, opts...)
+}
+
+// DecryptTransitCtx decrypts ciphertext like DecryptTransit, ctx cancels requests to Vault
+func DecryptTransitCtx(ctx context.Context, vc *VaultClient, cfg interface{}, opts ...transitOption) error {
+	if cfg == nil {
+		return nil
+	}
+	s := &transitSettings{mount: defaultTransitMount}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var slots []referenceSlot
+	collectReferenceSlots(reflect.ValueOf(cfg), "", "", nil, &slots)
+
+	byKey := map[string][]referenceSlot{}
+	var keys []string
+	for _, slot := range slots {
+		if !transitCiphertextPattern.MatchString(slot.value.String()) {
+			continue
+		}
+		key := slot.tag.Get(transitTag)
+		if key == "" {
+			key = s.key
+		}
+		if key == "" {
+			return fmt.Errorf("field %s: transit key is not set, use gotransit tag or WithTransitKey", slot.path)
+		}
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], slot)
+	}
+
+	for _, key := range keys {
+		if err := vc.decryptTransitSlots(ctx, s.mount, key, byKey[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptTransitSlots decrypts unique ciphertexts of slots in batches and sets plaintext only if all of them succeed
+func (vc *VaultClient) decryptTransitSlots(ctx context.Context, mount, key string, slots []referenceSlot) error {
+	var ciphertexts []string
+	fields := map[string]string{}
+	for _, slot := range slots {
+		ciphertext := slot.value.String()
+		if _, ok := fields[ciphertext]; !ok {
+			fields[ciphertext] = slot.path
+			ciphertexts = append(ciphertexts, ciphertext)
+		}
+	}
+
+	plaintexts := make(map[string]string, len(ciphertexts))
+	for start := 0; start < len(ciphertexts); start += transitBatchSize {
+		end := start + transitBatchSize
+		if end > len(ciphertexts) {
+			end = len(ciphertexts)
+		}
+		batch := ciphertexts[start:end]
+		results, err := vc.transitDecrypt(ctx, mount, key, batch)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", fields[batch[0]], err)
+		}
+		for i, ciphertext := range batch {
+			if results[i].err != nil {
+				return fmt.Errorf("field %s: %w", fields[ciphertext], results[i].err)
+			}
+			plaintexts[ciphertext] = results[i].plaintext
+		}
+	}
+
+	for _, slot := range slots {
+		markSecretPath(ctx, slot.path)
+		slot.set(reflect.ValueOf(plaintexts[slot.value.String()]).Convert(slot.value.Type()))
+	}
+	return nil
+}
+
+type transitResult struct {
+	plaintext string
+	err       error
+}
+
+// transitDecrypt sends batch decrypt request, results are returned in order of ciphertexts
+func (vc *VaultClient) transitDecrypt(ctx context.Context, mount, key string, ciphertexts []string) ([]transitResult, error) {
+	input := make([]map[string]interface{}, 0, len(ciphertexts))
+	for _, ciphertext := range ciphertexts {
+		input = append(input, map[string]interface{}{"ciphertext": ciphertext})
+	}
+	path := mount + "/decrypt/" + key
+	secret, err := vc.request(ctx, http.MethodPut, path, map[string]interface{}{"batch_input": input}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("transit decrypt with key %s: %w", key, err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("transit decrypt with key %s: %w: %s", key, ErrVaultPathNotFound, path)
+	}
+	batch, _ := secret.Data["batch_results"].([]interface{})
+	if len(batch) != len(ciphertexts) {
+		return nil, fmt.Errorf("transit decrypt with key %s: expected %d results, got %d", key, len(ciphertexts), len(batch))
+	}
+	results := make([]transitResult, len(batch))
+	for i, item := range batch {
+		result, _ := item.(map[string]interface{})
+		if msg, _ := result["error"].(string); msg != "" {
+			results[i].err = fmt.Errorf("transit decrypt with key %s: %s", key, msg)
+			continue
+		}
+		encoded, _ := result["plaintext"].(string)
+		plaintext, decodeErr := base64.StdEncoding.DecodeString(encoded)
+		if decodeErr != nil {
+			// decode error could contain part of plaintext, so it is not wrapped
+			results[i].err = errors.New("transit decrypt returned invalid base64 plaintext")
+			continue
+		}
+		results[i].plaintext = string(plaintext)
+	}
+	return results, nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type transitTestConfig struct {
+	Name     string            `yaml:"name"`
+	Password string            `yaml:"password" gotransit:"app"`
+	Token    string            `yaml:"token"`
+	Keys     []string          `yaml:"keys" gotransit:"keys"`
+	Extra    map[string]string `yaml:"extra"`
+}
+
+// transitTestServer decrypts "vault:vN:<plaintext>" ciphertext, "vault:v1:bad" fails
+type transitTestServer struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (s *transitTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		BatchInput []map[string]string `json:"batch_input"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	s.mu.Unlock()
+	results := make([]interface{}, 0, len(body.BatchInput))
+	for _, input := range body.BatchInput {
+		plaintext := input["ciphertext"][strings.LastIndex(input["ciphertext"], ":")+1:]
+		if plaintext == "bad" {
+			results = append(results, map[string]interface{}{"error": "cipher: message authentication failed"})
+			continue
+		}
+		results = append(results, map[string]interface{}{"plaintext": base64.StdEncoding.EncodeToString([]byte(plaintext))})
+	}
+	writeVaultResponse(w, map[string]interface{}{"batch_results": results})
+}
+
+func TestDecryptTransit(t *testing.T) {
+	// prepare
+	fake := &transitTestServer{requests: map[string]int{}}
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+	data := []byte(`
+name: app
+password: vault:v1:pass
+token: vault:v2:token
+keys: [vault:v1:first, vault:v1:second, vault:v1:first]
+extra:
+  plain: value
+  secret: vault:v1:pass
+`)
+	cfg := &transitTestConfig{}
+
+	// make test
+	err := NewConfig(cfg, WithParsingBytes(data, YAML), WithTransitDecryption(cli, WithTransitKey("default"), WithTransitMount("encryption")))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, &transitTestConfig{
+		Name:     "app",
+		Password: "pass",
+		Token:    "token",
+		Keys:     []string{"first", "second", "first"},
+		Extra:    map[string]string{"plain": "value", "secret": "pass"},
+	}, cfg)
+	assert.Equal(t, map[string]int{
+		"/v1/encryption/decrypt/app":     1,
+		"/v1/encryption/decrypt/keys":    1,
+		"/v1/encryption/decrypt/default": 1,
+	}, fake.requests)
+}
+
+func TestDecryptTransit_Fails_NoKey(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&transitTestServer{requests: map[string]int{}}).handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &transitTestConfig{Token: "vault:v1:token"}
+
+	// make test
+	err := DecryptTransit(cli, cfg)
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Token")
+}
+
+func TestDecryptTransit_Fails_DecryptError(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&transitTestServer{requests: map[string]int{}}).handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &transitTestConfig{Password: "vault:v1:bad", Keys: []string{"vault:v1:first"}}
+
+	// make test
+	err := DecryptTransit(cli, cfg)
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "field Password")
+	assert.Equal(t, "vault:v1:bad", cfg.Password)
+}
+
+func TestReloader_Reload_RedactsDecryptedTransit(t *testing.T) {
+	// prepare
+	fake := &transitTestServer{requests: map[string]int{}}
+	cli := newVaultTestClient(t, newVaultTestServer(t, fake.handler))
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadFile(t, path, "name: app\ntoken: vault:v1:plain-one\nextra:\n  secret: vault:v1:plain-one\n")
+	cfg := &transitTestConfig{}
+	logs := &bytes.Buffer{}
+	r := newTestReloader(t, cfg, WithParsingFile(path, YAML), WithTransitDecryption(cli, WithTransitKey("default")))
+	r.Logger = log.New(logs, "", 0)
+	writeReloadFile(t, path, "name: app\ntoken: vault:v2:plain-two\nextra:\n  secret: vault:v2:plain-two\n")
+
+	// make test
+	reloadErr := r.Reload()
+
+	// assertions
+	assert.Nil(t, reloadErr)
+	assert.Equal(t, "plain-two", cfg.Token)
+	assert.Equal(t, []string{"Token", "Extra"}, r.Status().Changed)
+	assert.Contains(t, logs.String(), "Token changed from [REDACTED] to [REDACTED]")
+	assert.Contains(t, logs.String(), "Extra changed from [REDACTED] to [REDACTED]")
+	assert.NotContains(t, logs.String(), "plain-")
+}
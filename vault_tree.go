@@ -0,0 +1,212 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	defaultTreeMaxDepth = 10
+)
+
+type treeSettings struct {
+	maxDepth    int
+	include     []string
+	exclude     []string
+	parallelism int
+}
+
+type treeOption func(s *treeSettings)
+
+// WithTreeMaxDepth sets how many folder levels below prefix are walked, secrets directly under prefix have depth 0
+// Default is 10, negative value means no limit
+func WithTreeMaxDepth(depth int) treeOption {
+	return func(s *treeSettings) {
+		s.maxDepth = depth
+	}
+}
+
+// WithTreeInclude reads only secrets which subpath matches one of path.Match patterns, e.g. "features/*"
+func WithTreeInclude(patterns ...string) treeOption {
+	return func(s *treeSettings) {
+		s.include = patterns
+	}
+}
+
+// WithTreeExclude skips secrets which subpath matches one of path.Match patterns
+func WithTreeExclude(patterns ...string) treeOption {
+	return func(s *treeSettings) {
+		s.exclude = patterns
+	}
+}
+
+// WithTreeParallelism sets how many secrets are read at once, see ReadBatch
+func WithTreeParallelism(parallelism int) treeOption {
+	return func(s *treeSettings) {
+		s.parallelism = parallelism
+	}
+}
+
+/*
+ReadTree lists KV prefix recursively and reads every secret under it, path syntax is the same as for ReadData
+Returning nested map keyed by subpath, data of "secret/app/features/db" is stored under ["features"]["db"]
+If secret and folder have the same name, e.g. "features/db" and "features/db/replica", their keys are merged,
+the same key in both of them is an error
+Deleted secrets are skipped, Returning error wrapping ErrVaultPathNotFound if there is nothing under prefix
+
+Example:
+tree, err := cli.ReadTree("secret/app", WithTreeMaxDepth(2), WithTreeExclude("legacy/*"))
+*/
+func (vc *VaultClient) ReadTree(prefix string, opts ...treeOption) (map[string]interface{}, error) {
+	return vc.ReadTreeCtx(context.Background(), prefix, opts...)
+}
+
+// ReadTreeInto reads tree like ReadTree and unmarshalls it to cfg
+// cfg should be passed as pointer
+func (vc *VaultClient) ReadTreeInto(prefix string, cfg interface{}, opts ...treeOption) error {
+	return vc.ReadTreeIntoCtx(context.Background(), prefix, cfg, opts...)
+}
+
+// ReadTreeIntoCtx reads tree like ReadTreeInto, ctx cancels listing and reading
+func (vc *VaultClient) ReadTreeIntoCtx(ctx context.Context, prefix string, cfg interface{}, opts ...treeOption) error {
+	tree, err := vc.ReadTreeCtx(ctx, prefix, opts...)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return ParseBytes(data, JSON, cfg)
+}
+
+// ReadTreeCtx reads tree like ReadTree, ctx cancels listing and reading
+func (vc *VaultClient) ReadTreeCtx(ctx context.Context, prefix string, opts ...treeOption) (map[string]interface{}, error) {
+	s := &treeSettings{maxDepth: defaultTreeMaxDepth}
+	for _, opt := range opts {
+		opt(s)
+	}
+	prefix = strings.Trim(prefix, "/") + "/"
+
+	leaves, err := vc.listTree(ctx, prefix, s)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(leaves))
+	for _, leaf := range leaves {
+		paths = append(paths, prefix+leaf)
+	}
+	batch := vc.ReadBatchCtx(ctx, paths, s.parallelism)
+
+	tree := map[string]interface{}{}
+	found := 0
+	for _, leaf := range leaves {
+		result := batch.Results[prefix+leaf]
+		switch {
+		case errors.Is(result.Err, ErrVaultPathNotFound):
+			// LIST returns soft-deleted KV v2 secrets as well
+			continue
+		case result.Err != nil:
+			return nil, fmt.Errorf("path %s%s: %w", prefix, leaf, result.Err)
+		}
+		if err := insertTreeLeaf(tree, strings.Split(leaf, "/"), result.Data); err != nil {
+			return nil, fmt.Errorf("%s%s: %w", prefix, leaf, err)
+		}
+		found++
+	}
+	if found == 0 {
+		return nil, vaultPathNotFound(strings.TrimSuffix(prefix, "/"))
+	}
+	return tree, nil
+}
+
+// listTree returns subpaths of secrets under prefix which pass depth limit and filters
+func (vc *VaultClient) listTree(ctx context.Context, prefix string, s *treeSettings) ([]string, error) {
+	mount := vc.detectKVMount(ctx, prefix)
+	var leaves []string
+	folders := []string{""}
+	for depth := 0; len(folders) > 0 && (s.maxDepth < 0 || depth <= s.maxDepth); depth++ {
+		var next []string
+		for _, folder := range folders {
+			keys, err := vc.listKeys(ctx, mount.metadataPath(prefix+folder))
+			if err != nil {
+				return nil, err
+			}
+			if keys == nil && depth == 0 {
+				return nil, vaultPathNotFound(prefix)
+			}
+			for _, key := range keys {
+				subpath := folder + key
+				if strings.HasSuffix(key, "/") {
+					next = append(next, subpath)
+					continue
+				}
+				if treeLeafMatches(subpath, s) {
+					leaves = append(leaves, subpath)
+				}
+			}
+		}
+		folders = next
+	}
+	return leaves, nil
+}
+
+// listKeys returns keys stored under path, nil if there are no keys
+func (vc *VaultClient) listKeys(ctx context.Context, path string) ([]string, error) {
+	secret, err := vc.request(ctx, "LIST", path, nil, nil)
+	if err != nil || secret == nil {
+		return nil, err
+	}
+	raw, _ := secret.Data["keys"].([]interface{})
+	keys := make([]string, 0, len(raw))
+	for _, k := range raw {
+		if key, ok := k.(string); ok && key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func treeLeafMatches(subpath string, s *treeSettings) bool {
+	for _, pattern := range s.exclude {
+		if ok, _ := path.Match(pattern, subpath); ok {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, pattern := range s.include {
+		if ok, _ := path.Match(pattern, subpath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// insertTreeLeaf stores data under nested keys, folders and secrets with the same name share a map
+func insertTreeLeaf(tree map[string]interface{}, keys []string, data map[string]interface{}) error {
+	node := tree
+	for _, key := range keys {
+		child, ok := node[key].(map[string]interface{})
+		if !ok {
+			if _, exists := node[key]; exists {
+				return fmt.Errorf("key %s is both a value and a folder", key)
+			}
+			child = map[string]interface{}{}
+			node[key] = child
+		}
+		node = child
+	}
+	for k, v := range data {
+		if _, exists := node[k]; exists {
+			return fmt.Errorf("key %s is both a value and a folder", k)
+		}
+		node[k] = v
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vielendanke/go-config/configtest"
+)
+
+// newTreeTestVault starts fake Vault with secrets under secret/app, "features/db" is both a secret and a folder
+func newTreeTestVault(t *testing.T) *configtest.Vault {
+	vault := configtest.NewVault(t)
+	vault.WriteSecret("secret/app/common", map[string]interface{}{"name": "app"})
+	vault.WriteSecret("secret/app/features/db", map[string]interface{}{"host": "db.local"})
+	vault.WriteSecret("secret/app/features/db/replica", map[string]interface{}{"host": "replica.local"})
+	vault.WriteSecret("secret/app/features/cache", map[string]interface{}{"ttl": "5m"})
+	vault.WriteSecret("secret/app/legacy/old", map[string]interface{}{"enabled": false})
+	return vault
+}
+
+type treeTestConfig struct {
+	Common   map[string]string `json:"common"`
+	Features struct {
+		DB struct {
+			Host    string            `json:"host"`
+			Replica map[string]string `json:"replica"`
+		} `json:"db"`
+		Cache map[string]string `json:"cache"`
+	} `json:"features"`
+}
+
+func TestVaultClient_ReadTree(t *testing.T) {
+	// prepare
+	cli := newFakeVaultClient(t, newTreeTestVault(t))
+
+	// make test
+	tree, err := cli.ReadTree("secret/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"common": map[string]interface{}{"name": "app"},
+		"features": map[string]interface{}{
+			"db":    map[string]interface{}{"host": "db.local", "replica": map[string]interface{}{"host": "replica.local"}},
+			"cache": map[string]interface{}{"ttl": "5m"},
+		},
+		"legacy": map[string]interface{}{"old": map[string]interface{}{"enabled": false}},
+	}, tree)
+}
+
+func TestVaultClient_ReadTree_DepthAndFilters(t *testing.T) {
+	// prepare
+	cli := newFakeVaultClient(t, newTreeTestVault(t))
+
+	// make test
+	shallow, shallowErr := cli.ReadTree("secret/app", WithTreeMaxDepth(0))
+	filtered, filteredErr := cli.ReadTree("secret/app", WithTreeInclude("features/*", "common"), WithTreeExclude("*/cache"))
+
+	// assertions
+	assert.Nil(t, shallowErr)
+	assert.Nil(t, filteredErr)
+	assert.Equal(t, map[string]interface{}{"common": map[string]interface{}{"name": "app"}}, shallow)
+	assert.Equal(t, map[string]interface{}{
+		"common":   map[string]interface{}{"name": "app"},
+		"features": map[string]interface{}{"db": map[string]interface{}{"host": "db.local"}},
+	}, filtered)
+}
+
+func TestNewConfig_WithParsingVaultTree(t *testing.T) {
+	// prepare
+	cli := newFakeVaultClient(t, newTreeTestVault(t))
+	cfg := &treeTestConfig{}
+
+	// make test
+	err := NewConfig(cfg, WithParsingVaultTree(cli, "secret/app/", WithTreeParallelism(2)))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, "app", cfg.Common["name"])
+	assert.Equal(t, "db.local", cfg.Features.DB.Host)
+	assert.Equal(t, "replica.local", cfg.Features.DB.Replica["host"])
+	assert.Equal(t, "5m", cfg.Features.Cache["ttl"])
+}
+
+func TestVaultClient_ReadTree_Fails_PrefixNotFound(t *testing.T) {
+	// prepare
+	cli := newFakeVaultClient(t, newTreeTestVault(t))
+
+	// make test
+	_, err := cli.ReadTree("secret/missing")
+
+	// assertions
+	assert.True(t, errors.Is(err, ErrVaultPathNotFound))
+}
+
+func TestVaultClient_ReadTree_SkipsDeletedSecrets(t *testing.T) {
+	// prepare
+	vault := newTreeTestVault(t)
+	vault.DeleteSecret("secret/app/legacy/old")
+	vault.DeleteSecret("secret/app/features/cache")
+	cli := newFakeVaultClient(t, vault)
+
+	// make test
+	tree, err := cli.ReadTree("secret/app")
+	_, legacyErr := cli.ReadTree("secret/app/legacy")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"common": map[string]interface{}{"name": "app"},
+		"features": map[string]interface{}{
+			"db": map[string]interface{}{"host": "db.local", "replica": map[string]interface{}{"host": "replica.local"}},
+		},
+	}, tree)
+	assert.True(t, errors.Is(legacyErr, ErrVaultPathNotFound))
+}
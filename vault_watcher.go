@@ -0,0 +1,324 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultWatcherMinTTL        = 10 * time.Second
+	defaultWatcherRetryInterval = 5 * time.Second
+	watcherEventsBuffer         = 16
+	// renewal happens after this part of TTL has passed
+	watcherRenewFraction = 2.0 / 3.0
+)
+
+// VaultEventType describes what happened with token or lease
+type VaultEventType int
+
+const (
+	// TokenRenewed is sent when client token was renewed
+	TokenRenewed VaultEventType = iota
+	// TokenReauthenticated is sent when client token could not be renewed and client logged in again
+	TokenReauthenticated
+	// LeaseRenewed is sent when lease of watched secret was renewed
+	LeaseRenewed
+	// LeaseExpired is sent when lease of watched secret could not be renewed anymore, secret should be read again
+	LeaseExpired
+	// WatcherError is sent when renewal or re-authentication failed, watcher retries it later
+	WatcherError
+	// CredentialsRotated is sent when dynamic credentials were replaced with new ones
+	CredentialsRotated
+)
+
+func (t VaultEventType) String() string {
+	switch t {
+	case TokenRenewed:
+		return "token renewed"
+	case TokenReauthenticated:
+		return "token reauthenticated"
+	case LeaseRenewed:
+		return "lease renewed"
+	case LeaseExpired:
+		return "lease expired"
+	case WatcherError:
+		return "error"
+	case CredentialsRotated:
+		return "credentials rotated"
+	default:
+		return "unknown"
+	}
+}
+
+// VaultEvent is sent by VaultWatcher on every renewal attempt
+type VaultEvent struct {
+	Type VaultEventType
+	// LeaseID is set for lease events
+	LeaseID string
+	// TTL is a new TTL of token or lease
+	TTL time.Duration
+	Err error
+}
+
+/*
+VaultWatcher renews client token and leases of watched secrets before they expire
+If token could not be renewed anymore, client logs in again with its auth method
+Events are sent to the channel returned by Events and to the callback set with WithWatcherCallback,
+the channel is closed when Run returns, so Run should be called only once
+
+Example:
+w := NewVaultWatcher(cli)
+secret, err := cli.Read("database/creds/app")
+w.WatchLease(secret)
+go w.Run(ctx)
+for e := range w.Events() { ... }
+*/
+type VaultWatcher struct {
+	vc            *VaultClient
+	events        chan VaultEvent
+	callback      func(VaultEvent)
+	minTTL        time.Duration
+	retryInterval time.Duration
+	wake          chan struct{}
+
+	mu       sync.Mutex
+	tokenDue time.Time
+	leases   map[string]*watchedLease
+}
+
+type watchedLease struct {
+	id  string
+	due time.Time
+}
+
+type watcherOption func(w *VaultWatcher)
+
+// WithWatcherCallback sets function called synchronously for every event
+func WithWatcherCallback(callback func(VaultEvent)) watcherOption {
+	return func(w *VaultWatcher) {
+		w.callback = callback
+	}
+}
+
+// WithWatcherMinTTL sets TTL below which token is not renewed but reauthenticated and lease is considered as expired, default is 10 seconds
+func WithWatcherMinTTL(ttl time.Duration) watcherOption {
+	return func(w *VaultWatcher) {
+		w.minTTL = ttl
+	}
+}
+
+// WithWatcherRetryInterval sets wait before retry of failed renewal, default is 5 seconds
+func WithWatcherRetryInterval(interval time.Duration) watcherOption {
+	return func(w *VaultWatcher) {
+		w.retryInterval = interval
+	}
+}
+
+// NewVaultWatcher creates watcher for client token, leases are added with WatchLease
+func NewVaultWatcher(vc *VaultClient, opts ...watcherOption) *VaultWatcher {
+	w := &VaultWatcher{
+		vc:            vc,
+		events:        make(chan VaultEvent, watcherEventsBuffer),
+		minTTL:        defaultWatcherMinTTL,
+		retryInterval: defaultWatcherRetryInterval,
+		wake:          make(chan struct{}, 1),
+		leases:        map[string]*watchedLease{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Events returns channel with events, events are dropped if channel buffer is full, channel is closed when Run returns
+func (w *VaultWatcher) Events() <-chan VaultEvent {
+	return w.events
+}
+
+// WatchLease adds lease of secret to renewal, secrets without lease or not renewable are ignored
+func (w *VaultWatcher) WatchLease(secret *api.Secret) {
+	if secret == nil || secret.LeaseID == "" || !secret.Renewable {
+		return
+	}
+	w.mu.Lock()
+	w.leases[secret.LeaseID] = &watchedLease{id: secret.LeaseID, due: renewAt(secret.LeaseDuration)}
+	w.mu.Unlock()
+	w.notify()
+}
+
+// UnwatchLease stops renewal of lease
+func (w *VaultWatcher) UnwatchLease(leaseID string) {
+	w.mu.Lock()
+	delete(w.leases, leaseID)
+	w.mu.Unlock()
+	w.notify()
+}
+
+// Run renews token and leases until ctx is done, renewals failed because Vault is unreachable are retried
+func (w *VaultWatcher) Run(ctx context.Context) error {
+	defer close(w.events)
+	w.refreshToken(ctx)
+	for {
+		timer := time.NewTimer(time.Until(w.nextDue()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-w.wake:
+			timer.Stop()
+		case <-timer.C:
+			w.renewDue(ctx)
+		}
+	}
+}
+
+func (w *VaultWatcher) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *VaultWatcher) nextDue() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	next := time.Now().Add(time.Hour)
+	if !w.tokenDue.IsZero() && w.tokenDue.Before(next) {
+		next = w.tokenDue
+	}
+	for _, l := range w.leases {
+		if l.due.Before(next) {
+			next = l.due
+		}
+	}
+	return next
+}
+
+func (w *VaultWatcher) renewDue(ctx context.Context) {
+	now := time.Now()
+	w.mu.Lock()
+	tokenDue := !w.tokenDue.IsZero() && !w.tokenDue.After(now)
+	var due []string
+	for id, l := range w.leases {
+		if !l.due.After(now) {
+			due = append(due, id)
+		}
+	}
+	w.mu.Unlock()
+
+	if tokenDue {
+		w.renewToken(ctx)
+	}
+	for _, id := range due {
+		w.renewLease(ctx, id)
+	}
+}
+
+// refreshToken looks up current token and schedules its renewal, non expiring tokens are not renewed
+func (w *VaultWatcher) refreshToken(ctx context.Context) {
+	secret, err := w.vc.request(ctx, http.MethodGet, "auth/token/lookup-self", nil, nil)
+	if err == nil && secret == nil {
+		err = errors.New("token lookup returned empty response")
+	}
+	if err != nil {
+		w.emit(VaultEvent{Type: WatcherError, Err: err})
+		w.setTokenDue(time.Now().Add(w.retryInterval))
+		return
+	}
+	ttl, _ := secret.TokenTTL()
+	renewable, _ := secret.TokenIsRenewable()
+	switch {
+	case ttl == 0:
+		w.setTokenDue(time.Time{})
+	case !renewable || ttl < w.minTTL:
+		// token could not be extended, login again just before it expires
+		w.setTokenDue(time.Now().Add(ttl - ttl/10))
+	default:
+		w.setTokenDue(renewAt(int(ttl.Seconds())))
+	}
+}
+
+func (w *VaultWatcher) renewToken(ctx context.Context) {
+	token := w.vc.client.Token()
+	secret, err := w.vc.request(ctx, http.MethodPut, "auth/token/renew-self", map[string]interface{}{}, nil)
+	if err == nil && secret != nil && secret.Auth != nil && secret.Auth.Renewable &&
+		time.Duration(secret.Auth.LeaseDuration)*time.Second >= w.minTTL {
+		w.vc.setTokenTTL(secret.Auth.LeaseDuration)
+		w.setTokenDue(renewAt(secret.Auth.LeaseDuration))
+		w.emit(VaultEvent{Type: TokenRenewed, TTL: time.Duration(secret.Auth.LeaseDuration) * time.Second})
+		return
+	}
+	if isVaultUnreachable(err) {
+		w.emit(VaultEvent{Type: WatcherError, Err: err})
+		w.setTokenDue(time.Now().Add(w.retryInterval))
+		return
+	}
+	if w.vc.auth == nil {
+		if err == nil {
+			err = errors.New("token can not be renewed anymore and auth method is not configured")
+		}
+		w.emit(VaultEvent{Type: WatcherError, Err: err})
+		w.setTokenDue(time.Time{})
+		return
+	}
+	if authErr := w.vc.reauthenticate(ctx, token); authErr != nil {
+		w.emit(VaultEvent{Type: WatcherError, Err: authErr})
+		w.setTokenDue(time.Now().Add(w.retryInterval))
+		return
+	}
+	w.refreshToken(ctx)
+	w.emit(VaultEvent{Type: TokenReauthenticated})
+}
+
+func (w *VaultWatcher) renewLease(ctx context.Context, leaseID string) {
+	secret, err := w.vc.request(ctx, http.MethodPut, "sys/leases/renew", map[string]interface{}{"lease_id": leaseID}, nil)
+	if err == nil && secret != nil && secret.Renewable && time.Duration(secret.LeaseDuration)*time.Second >= w.minTTL {
+		w.mu.Lock()
+		if l, ok := w.leases[leaseID]; ok {
+			l.due = renewAt(secret.LeaseDuration)
+		}
+		w.mu.Unlock()
+		w.emit(VaultEvent{Type: LeaseRenewed, LeaseID: leaseID, TTL: time.Duration(secret.LeaseDuration) * time.Second})
+		return
+	}
+	if isVaultUnreachable(err) {
+		// lease could be still valid, it is considered as expired only when Vault says so
+		w.mu.Lock()
+		if l, ok := w.leases[leaseID]; ok {
+			l.due = time.Now().Add(w.retryInterval)
+		}
+		w.mu.Unlock()
+		w.emit(VaultEvent{Type: WatcherError, LeaseID: leaseID, Err: err})
+		return
+	}
+	w.mu.Lock()
+	delete(w.leases, leaseID)
+	w.mu.Unlock()
+	w.emit(VaultEvent{Type: LeaseExpired, LeaseID: leaseID, Err: err})
+}
+
+func (w *VaultWatcher) setTokenDue(due time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tokenDue = due
+}
+
+func (w *VaultWatcher) emit(e VaultEvent) {
+	if w.callback != nil {
+		w.callback(e)
+	}
+	select {
+	case w.events <- e:
+	default:
+	}
+}
+
+// renewAt returns time when lease with ttl in seconds should be renewed
+func renewAt(ttl int) time.Time {
+	return time.Now().Add(time.Duration(float64(time.Duration(ttl)*time.Second) * watcherRenewFraction))
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/vielendanke/go-config/configtest"
+)
+
+type watcherTestServer struct {
+	tokenTTL  int
+	renewable bool
+	renewTTL  int
+	leaseTTL  int
+}
+
+func (s *watcherTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/v1/auth/token/lookup-self":
+		writeVaultResponse(w, map[string]interface{}{"ttl": s.tokenTTL, "renewable": true})
+	case "/v1/auth/token/renew-self":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": r.Header.Get("X-Vault-Token"), "lease_duration": s.renewTTL, "renewable": s.renewable},
+		})
+	case "/v1/sys/leases/renew":
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"lease_id": body["lease_id"], "lease_duration": s.leaseTTL, "renewable": true})
+	}
+}
+
+type staticTestAuth string
+
+func (s staticTestAuth) Login(ctx context.Context, cli *api.Client) (*api.Secret, error) {
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: string(s)}}, nil
+}
+
+func waitVaultEvent(t *testing.T, w *VaultWatcher) VaultEvent {
+	select {
+	case e := <-w.Events():
+		return e
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event received")
+		return VaultEvent{}
+	}
+}
+
+func watcherTokenDue(w *VaultWatcher) time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.tokenDue
+}
+
+func runVaultWatcher(t *testing.T, w *VaultWatcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.True(t, errors.Is(<-done, context.Canceled))
+	})
+}
+
+func TestVaultWatcher_RenewsToken(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&watcherTestServer{tokenTTL: 1, renewable: true, renewTTL: 3600}).handler)
+	cli := newVaultTestClient(t, srv)
+	var callbackEvents []VaultEvent
+	w := NewVaultWatcher(cli, WithWatcherMinTTL(0), WithWatcherCallback(func(e VaultEvent) {
+		callbackEvents = append(callbackEvents, e)
+	}))
+
+	// make test
+	runVaultWatcher(t, w)
+	event := waitVaultEvent(t, w)
+
+	// assertions
+	assert.Equal(t, TokenRenewed, event.Type)
+	assert.Equal(t, time.Hour, event.TTL)
+	assert.Equal(t, []VaultEvent{event}, callbackEvents)
+}
+
+func TestVaultWatcher_ReauthenticatesWhenRenewalIsNotPossible(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&watcherTestServer{tokenTTL: 1, renewable: false, renewTTL: 1}).handler)
+	cli := newVaultTestClient(t, srv, WithVaultAuth(staticTestAuth("relogin")))
+	w := NewVaultWatcher(cli, WithWatcherMinTTL(0))
+
+	// make test
+	runVaultWatcher(t, w)
+	event := waitVaultEvent(t, w)
+
+	// assertions
+	assert.Equal(t, TokenReauthenticated, event.Type)
+	assert.Equal(t, "relogin", cli.client.Token())
+}
+
+func TestVaultWatcher_FailsWithoutAuthMethod(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&watcherTestServer{tokenTTL: 1, renewable: false, renewTTL: 1}).handler)
+	cli := newVaultTestClient(t, srv)
+	w := NewVaultWatcher(cli, WithWatcherMinTTL(0))
+
+	// make test
+	runVaultWatcher(t, w)
+	event := waitVaultEvent(t, w)
+
+	// assertions
+	assert.Equal(t, WatcherError, event.Type)
+	assert.NotNil(t, event.Err)
+}
+
+func TestVaultWatcher_RenewsLease(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&watcherTestServer{leaseTTL: 3600}).handler)
+	cli := newVaultTestClient(t, srv)
+	w := NewVaultWatcher(cli, WithWatcherMinTTL(0))
+	w.WatchLease(&api.Secret{LeaseID: "database/creds/app/1", LeaseDuration: 1, Renewable: true})
+	w.WatchLease(&api.Secret{LeaseID: "static", LeaseDuration: 1})
+
+	// make test
+	runVaultWatcher(t, w)
+	event := waitVaultEvent(t, w)
+
+	// assertions
+	assert.Equal(t, LeaseRenewed, event.Type)
+	assert.Equal(t, "database/creds/app/1", event.LeaseID)
+	assert.Equal(t, time.Hour, event.TTL)
+}
+
+func TestVaultWatcher_LeaseExpires(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, (&watcherTestServer{leaseTTL: 1}).handler)
+	cli := newVaultTestClient(t, srv)
+	w := NewVaultWatcher(cli, WithWatcherMinTTL(time.Minute))
+	w.WatchLease(&api.Secret{LeaseID: "database/creds/app/1", LeaseDuration: 1, Renewable: true})
+
+	// make test
+	runVaultWatcher(t, w)
+	event := waitVaultEvent(t, w)
+
+	// assertions
+	assert.Equal(t, LeaseExpired, event.Type)
+	assert.Equal(t, "database/creds/app/1", event.LeaseID)
+}
+
+func TestVaultWatcher_RetriesTokenRenewalWhenVaultIsUnreachable(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	cli := newFakeVaultClient(t, vault, WithVaultToken(vault.CreateToken(time.Hour, true)))
+	w := NewVaultWatcher(cli, WithWatcherMinTTL(0), WithWatcherRetryInterval(10*time.Millisecond))
+	// token lookup and the first renewal fail
+	vault.FailNext(2, http.StatusBadGateway)
+
+	// make test
+	runVaultWatcher(t, w)
+	lookupFailed := waitVaultEvent(t, w)
+	renewFailed := waitVaultEvent(t, w)
+	renewed := waitVaultEvent(t, w)
+
+	// assertions
+	assert.Equal(t, WatcherError, lookupFailed.Type)
+	assert.Equal(t, WatcherError, renewFailed.Type)
+	assert.NotNil(t, renewFailed.Err)
+	assert.Equal(t, TokenRenewed, renewed.Type)
+	assert.Equal(t, time.Hour, renewed.TTL)
+}
+
+func TestVaultWatcher_RetriesLeaseRenewalWhenVaultIsUnreachable(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	vault.WriteLeasedSecret("database/creds/app", map[string]interface{}{"username": "app"}, time.Hour, true)
+	cli := newFakeVaultClient(t, vault, WithVaultToken(vault.CreateToken(time.Hour, true)))
+	secret, readErr := cli.Read("database/creds/app")
+	w := NewVaultWatcher(cli, WithWatcherMinTTL(0), WithWatcherRetryInterval(10*time.Millisecond))
+	// lease is renewed in less than a second
+	w.WatchLease(&api.Secret{LeaseID: secret.LeaseID, LeaseDuration: 1, Renewable: true})
+
+	// make test
+	runVaultWatcher(t, w)
+	// wait for token lookup, so only lease renewal fails
+	for watcherTokenDue(w).IsZero() {
+		time.Sleep(time.Millisecond)
+	}
+	vault.FailNext(1, http.StatusServiceUnavailable)
+	failed := waitVaultEvent(t, w)
+	renewed := waitVaultEvent(t, w)
+
+	// assertions
+	assert.Nil(t, readErr)
+	assert.Equal(t, WatcherError, failed.Type)
+	assert.Equal(t, secret.LeaseID, failed.LeaseID)
+	assert.Equal(t, LeaseRenewed, renewed.Type)
+	assert.Equal(t, secret.LeaseID, renewed.LeaseID)
+	assert.Equal(t, []string{secret.LeaseID}, vault.Leases())
+}
+
+func TestVaultWatcher_ClosesEventsWhenStopped(t *testing.T) {
+	// prepare
+	vault := configtest.NewVault(t)
+	w := NewVaultWatcher(newFakeVaultClient(t, vault))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// make test
+	go func() {
+		for range w.Events() {
+		}
+		close(done)
+	}()
+	runErr := make(chan error)
+	go func() {
+		runErr <- w.Run(ctx)
+	}()
+	cancel()
+
+	// assertions
+	assert.True(t, errors.Is(<-runErr, context.Canceled))
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("events channel is not closed")
+	}
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// ErrVaultWrappingPath is returned when wrapping token was created at path which is not allowed, e.g. it was tampered
+var ErrVaultWrappingPath = errors.New("wrapping token creation path is not allowed")
+
+/*
+WrappedTokenAuth logs in with response-wrapped token, e.g. created with "vault token create -wrap-ttl=5m"
+Before unwrapping, creation path of wrapping token is looked up and checked against AllowedPaths
+(exact paths or path.Match patterns, e.g. "auth/token/create*"), so tampered wrap is detected before the token is used
+Wrapping token is single-use, TokenFile is read on each login, so orchestrator could hand a new one without restart
+
+Example:
+cli, err := NewVaultClient(WithVaultAuth(&WrappedTokenAuth{TokenFile: "/run/secrets/wrapped", AllowedPaths: []string{"auth/token/create"}}))
+*/
+type WrappedTokenAuth struct {
+	Token        string
+	TokenFile    string
+	AllowedPaths []string
+
+	version fileVersion
+	mu      sync.Mutex
+	used    string
+}
+
+// Login unwraps token via sys/wrapping/unwrap
+func (a *WrappedTokenAuth) Login(ctx context.Context, cli *api.Client) (*api.Secret, error) {
+	token := a.Token
+	if a.TokenFile != "" {
+		data, err := readCredentialsFile(a.TokenFile, &a.version)
+		if err != nil {
+			return nil, fmt.Errorf("reading wrapping token: %w", err)
+		}
+		token = data
+	}
+	if token == "" {
+		return nil, errors.New("wrapping token is not set, use Token or TokenFile")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if token == a.used {
+		return nil, errors.New("wrapping token has already been unwrapped, a new one is required")
+	}
+	secret, err := unwrapAllowed(ctx, clientSender(cli), token, a.AllowedPaths)
+	if err != nil {
+		return nil, err
+	}
+	a.used = token
+	return secret, nil
+}
+
+func (a *WrappedTokenAuth) rotated() bool {
+	return a.TokenFile != "" && a.version.changed(a.TokenFile)
+}
+
+/*
+Unwrap unwraps response-wrapped secret, creation path of wrapping token is checked against allowedPaths
+(exact paths or path.Match patterns) before unwrapping, at least one path is required
+Returning error wrapping ErrVaultWrappingPath if wrapping token was created at other path
+
+Example:
+secret, err := cli.Unwrap(token, "secret/data/app")
+*/
+func (vc *VaultClient) Unwrap(wrappingToken string, allowedPaths ...string) (*api.Secret, error) {
+	return vc.UnwrapCtx(context.Background(), wrappingToken, allowedPaths...)
+}
+
+// UnwrapCtx unwraps secret like Unwrap, ctx cancels lookup and unwrapping
+// Lookup and unwrapping are sent to namespace of vc, e.g. set with WithNamespace
+func (vc *VaultClient) UnwrapCtx(ctx context.Context, wrappingToken string, allowedPaths ...string) (*api.Secret, error) {
+	return unwrapAllowed(ctx, vc.sendWithToken, wrappingToken, allowedPaths)
+}
+
+// UnwrapInto unwraps response-wrapped KV secret like Unwrap and unmarshalls its data to cfg
+// cfg should be passed as pointer
+func (vc *VaultClient) UnwrapInto(wrappingToken string, cfg interface{}, allowedPaths ...string) error {
+	return vc.UnwrapIntoCtx(context.Background(), wrappingToken, cfg, allowedPaths...)
+}
+
+// UnwrapIntoCtx unwraps secret like UnwrapInto, ctx cancels lookup and unwrapping
+func (vc *VaultClient) UnwrapIntoCtx(ctx context.Context, wrappingToken string, cfg interface{}, allowedPaths ...string) error {
+	secret, err := vc.UnwrapCtx(ctx, wrappingToken, allowedPaths...)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(kvMount{}.unwrap(secret.Data))
+	if err != nil {
+		return err
+	}
+	return ParseBytes(data, JSON, cfg)
+}
+
+// unwrapAllowed looks up wrapping token, checks its creation path and unwraps it
+func unwrapAllowed(ctx context.Context, send vaultSender, wrappingToken string, allowedPaths []string) (*api.Secret, error) {
+	if len(allowedPaths) == 0 {
+		return nil, errors.New("allowed wrapping creation paths are not set")
+	}
+	creationPath, err := vaultWrappingCreationPath(ctx, send, wrappingToken)
+	if err != nil {
+		return nil, err
+	}
+	if !wrappingPathAllowed(creationPath, allowedPaths) {
+		return nil, fmt.Errorf("%w: %s", ErrVaultWrappingPath, creationPath)
+	}
+	secret, err := vaultUnwrap(ctx, send, wrappingToken)
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping token created at %s: %w", creationPath, err)
+	}
+	return secret, nil
+}
+
+// vaultWrappingCreationPath looks up wrapping token without using it
+func vaultWrappingCreationPath(ctx context.Context, send vaultSender, wrappingToken string) (string, error) {
+	secret, err := send(ctx, "sys/wrapping/lookup", "", map[string]interface{}{"token": wrappingToken})
+	if err != nil {
+		return "", fmt.Errorf("wrapping token lookup: %w", err)
+	}
+	if secret == nil {
+		return "", errors.New("wrapping token lookup: token is not found")
+	}
+	creationPath, _ := secret.Data["creation_path"].(string)
+	if creationPath == "" {
+		return "", errors.New("wrapping token lookup: response does not contain creation_path")
+	}
+	return creationPath, nil
+}
+
+func wrappingPathAllowed(creationPath string, allowedPaths []string) bool {
+	creationPath = strings.Trim(creationPath, "/")
+	for _, allowed := range allowedPaths {
+		allowed = strings.Trim(allowed, "/")
+		if allowed == creationPath {
+			return true
+		}
+		if ok, _ := path.Match(allowed, creationPath); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type wrappedTestResponse struct {
+	creationPath string
+	body         map[string]interface{}
+}
+
+// wrappingTestServer serves single-use wrapping tokens and records client tokens of secret reads
+type wrappingTestServer struct {
+	mu     sync.Mutex
+	tokens map[string]wrappedTestResponse
+	reads  []string
+}
+
+func (s *wrappingTestServer) handler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/v1/sys/wrapping/lookup":
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		wrapped, ok := s.tokens[body["token"]]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeVaultResponse(w, map[string]interface{}{"creation_path": wrapped.creationPath, "creation_ttl": 300})
+	case "/v1/sys/wrapping/unwrap":
+		token := r.Header.Get("X-Vault-Token")
+		wrapped, ok := s.tokens[token]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		delete(s.tokens, token)
+		_ = json.NewEncoder(w).Encode(wrapped.body)
+	default:
+		s.reads = append(s.reads, r.Header.Get("X-Vault-Token"))
+		writeVaultResponse(w, map[string]interface{}{"user": "admin"})
+	}
+}
+
+func newWrappingTestServer() *wrappingTestServer {
+	return &wrappingTestServer{tokens: map[string]wrappedTestResponse{
+		"wrapped-token": {
+			creationPath: "auth/token/create",
+			body:         map[string]interface{}{"auth": map[string]interface{}{"client_token": "unwrapped-token", "lease_duration": 3600}},
+		},
+		"wrapped-secret": {
+			creationPath: "secret/data/app",
+			body:         map[string]interface{}{"data": map[string]interface{}{"data": map[string]interface{}{"user": "admin", "password": "pass"}}},
+		},
+	}}
+}
+
+func TestWrappedTokenAuth(t *testing.T) {
+	// prepare
+	fake := newWrappingTestServer()
+	srv := newVaultTestServer(t, fake.handler)
+	tokenFile := filepath.Join(t.TempDir(), "wrapped")
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("wrapped-token\n"), 0600))
+	cli := newVaultTestClient(t, srv, WithVaultAuth(&WrappedTokenAuth{TokenFile: tokenFile, AllowedPaths: []string{"auth/token/create*"}}))
+
+	// make test
+	_, err := cli.Read("secret/app")
+	_, secondErr := cli.Read("secret/app")
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Nil(t, secondErr)
+	assert.Equal(t, []string{"unwrapped-token", "unwrapped-token"}, fake.reads)
+}
+
+func TestWrappedTokenAuth_Fails_CreationPathNotAllowed(t *testing.T) {
+	// prepare
+	fake := newWrappingTestServer()
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv, WithVaultAuth(&WrappedTokenAuth{Token: "wrapped-secret", AllowedPaths: []string{"auth/token/create"}}))
+
+	// make test
+	_, err := cli.Read("secret/app")
+
+	// assertions
+	assert.True(t, errors.Is(err, ErrVaultWrappingPath))
+	assert.Empty(t, fake.reads)
+	assert.Contains(t, fake.tokens, "wrapped-secret")
+}
+
+func TestWrappedTokenAuth_Fails_TokenAlreadyUsed(t *testing.T) {
+	// prepare
+	srv := newVaultTestServer(t, newWrappingTestServer().handler)
+	auth := &WrappedTokenAuth{Token: "wrapped-token", AllowedPaths: []string{"auth/token/create"}}
+	cli := newVaultTestClient(t, srv, WithVaultAuth(auth))
+	_, err := auth.Login(context.Background(), cli.client)
+	assert.Nil(t, err)
+
+	// make test
+	_, err = auth.Login(context.Background(), cli.client)
+
+	// assertions
+	assert.NotNil(t, err)
+}
+
+func TestVaultClient_UnwrapInto(t *testing.T) {
+	// prepare
+	fake := newWrappingTestServer()
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+	cfg := &vaultClientTestConfig{}
+
+	// make test
+	err := NewConfig(cfg, WithParsingVaultWrapped(cli, "wrapped-secret", "secret/data/*"))
+
+	// assertions
+	assert.Nil(t, err)
+	assert.Equal(t, &vaultClientTestConfig{User: "admin", Password: "pass"}, cfg)
+	assert.NotContains(t, fake.tokens, "wrapped-secret")
+}
+
+func TestVaultClient_Unwrap_Fails_NoAllowedPaths(t *testing.T) {
+	// prepare
+	fake := newWrappingTestServer()
+	srv := newVaultTestServer(t, fake.handler)
+	cli := newVaultTestClient(t, srv)
+
+	// make test
+	_, err := cli.Unwrap("wrapped-secret")
+
+	// assertions
+	assert.NotNil(t, err)
+	assert.Contains(t, fake.tokens, "wrapped-secret")
+}